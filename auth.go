@@ -51,7 +51,7 @@ func IsProxied(r *http.Request) bool {
 	// The header will only be set from external traffic, so we can check if the header is set to determine if the
 	// request is internal.
 
-	h := r.Header.Get("X-Forwarded-For")
+	h := r.Header.Get(headerForwardedFor)
 	// If the header is not set, then the request is internal.
 	return h == ""
 }
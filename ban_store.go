@@ -0,0 +1,188 @@
+package uhttp
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Ban is a temporary block on a key.
+type Ban struct {
+	// Key is the rate limit key that is banned.
+	Key string `json:"key"`
+
+	// Until is the time at which the ban expires.
+	Until time.Time `json:"until"`
+
+	// Strikes is the number of times the key has been banned, including this ban.
+	Strikes int `json:"strikes"`
+}
+
+// BanStore holds the violations, strikes and bans of the penalty box.
+type BanStore interface {
+	// AddViolation records a violation of the rate limit by the key and returns the number of violations since the
+	// first one in the window.
+	AddViolation(ctx context.Context, key string, now time.Time, window time.Duration) (int, error)
+
+	// AddStrike records that the key is being banned and returns the number of times it has been banned. Strikes are
+	// forgotten once the key has gone the given TTL without being banned.
+	AddStrike(ctx context.Context, key string, now time.Time, ttl time.Duration) (int, error)
+
+	// SetBan bans the key until the ban expires and clears its violations.
+	SetBan(ctx context.Context, ban *Ban, now time.Time) error
+
+	// GetBan returns the ban on the key. It returns false if the key is not banned.
+	GetBan(ctx context.Context, key string, now time.Time) (*Ban, bool, error)
+
+	// ListBans returns the bans in force.
+	ListBans(ctx context.Context, now time.Time) ([]*Ban, error)
+
+	// DeleteBan lifts the ban on the key and forgets its violations and strikes.
+	DeleteBan(ctx context.Context, key string) error
+}
+
+// expiringCount is a counter that is reset once it expires.
+type expiringCount struct {
+	count   int
+	expires time.Time
+}
+
+// memoryBanStore is a BanStore local to this instance.
+type memoryBanStore struct {
+	mu sync.Mutex
+
+	violations map[string]*expiringCount
+	strikes    map[string]*expiringCount
+	bans       map[string]*Ban
+
+	// pruned is when the expired entries were last removed.
+	pruned time.Time
+}
+
+// NewMemoryBanStore creates a new BanStore that holds the bans in memory. Bans are not shared between instances.
+func NewMemoryBanStore() BanStore {
+	return &memoryBanStore{
+		violations: make(map[string]*expiringCount),
+		strikes:    make(map[string]*expiringCount),
+		bans:       make(map[string]*Ban),
+	}
+}
+
+// AddViolation records a violation of the rate limit by the key.
+func (s *memoryBanStore) AddViolation(_ context.Context, key string, now time.Time, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Keys that stop violating the limit are never looked up again, so they are removed in bulk once a window.
+	if now.Sub(s.pruned) >= window {
+		s.prune(now)
+	}
+
+	return incrementExpiring(s.violations, key, now, window, false), nil
+}
+
+// AddStrike records that the key is being banned.
+func (s *memoryBanStore) AddStrike(_ context.Context, key string, now time.Time, ttl time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return incrementExpiring(s.strikes, key, now, ttl, true), nil
+}
+
+// SetBan bans the key until the ban expires.
+func (s *memoryBanStore) SetBan(_ context.Context, ban *Ban, _ time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.bans[ban.Key] = ban
+	delete(s.violations, ban.Key)
+	return nil
+}
+
+// GetBan returns the ban on the key.
+func (s *memoryBanStore) GetBan(_ context.Context, key string, now time.Time) (*Ban, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ban, ok := s.bans[key]
+	if !ok {
+		return nil, false, nil
+	} else if !now.Before(ban.Until) {
+		delete(s.bans, key)
+		return nil, false, nil
+	}
+
+	return ban, true, nil
+}
+
+// ListBans returns the bans in force, ordered by key.
+func (s *memoryBanStore) ListBans(_ context.Context, now time.Time) ([]*Ban, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bans := make([]*Ban, 0, len(s.bans))
+	for key, ban := range s.bans {
+		if !now.Before(ban.Until) {
+			delete(s.bans, key)
+			continue
+		}
+		bans = append(bans, ban)
+	}
+
+	slices.SortFunc(bans, func(a, b *Ban) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	return bans, nil
+}
+
+// DeleteBan lifts the ban on the key.
+func (s *memoryBanStore) DeleteBan(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.bans, key)
+	delete(s.violations, key)
+	delete(s.strikes, key)
+	return nil
+}
+
+// prune removes the expired violations, strikes and bans.
+func (s *memoryBanStore) prune(now time.Time) {
+	s.pruned = now
+
+	for key, c := range s.violations {
+		if !now.Before(c.expires) {
+			delete(s.violations, key)
+		}
+	}
+	for key, c := range s.strikes {
+		if !now.Before(c.expires) {
+			delete(s.strikes, key)
+		}
+	}
+	for key, ban := range s.bans {
+		if !now.Before(ban.Until) {
+			delete(s.bans, key)
+		}
+	}
+}
+
+// incrementExpiring increments the counter for the key, starting it again if it has expired. When extend is true the
+// expiry is pushed back on every increment, otherwise it is fixed by the first.
+func incrementExpiring(counts map[string]*expiringCount, key string, now time.Time, ttl time.Duration, extend bool) int {
+	c, ok := counts[key]
+	if !ok || !now.Before(c.expires) {
+		c = &expiringCount{
+			expires: now.Add(ttl),
+		}
+		counts[key] = c
+	} else if extend {
+		c.expires = now.Add(ttl)
+	}
+
+	c.count++
+	return c.count
+}
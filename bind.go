@@ -0,0 +1,325 @@
+package uhttp
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	bindSourceQuery  = "query"
+	bindSourcePath   = "path"
+	bindSourceHeader = "header"
+	bindSourceCookie = "cookie"
+	bindSourceForm   = "form"
+
+	// bindTagDefault is the struct tag holding the value used when the parameter is missing. Slices take a comma
+	// separated list.
+	bindTagDefault = "default"
+)
+
+var (
+	// bindSources are the struct tags Bind reads, in the order they are checked.
+	bindSources = []string{bindSourcePath, bindSourceQuery, bindSourceHeader, bindSourceCookie}
+
+	// bindFieldsCache caches the bindable fields of each struct type, keyed by bindFieldsKey.
+	bindFieldsCache sync.Map
+
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	timeType            = reflect.TypeFor[time.Time]()
+	durationType        = reflect.TypeFor[time.Duration]()
+)
+
+// bindFieldsKey identifies the bindable fields of a struct type for a set of sources.
+type bindFieldsKey struct {
+	typ     reflect.Type
+	sources string
+}
+
+// ParamError describes a request parameter that could not be bound.
+type ParamError struct {
+	// In is where the parameter was read from: "query", "path", "header", "cookie" or "form".
+	In string `json:"in" xml:"in"`
+
+	// Name is the name of the parameter.
+	Name string `json:"name" xml:"name"`
+
+	// Message describes why the value could not be bound.
+	Message string `json:"message" xml:"message"`
+}
+
+// BindError is returned by Bind when parameters cannot be converted. It is written as a 400 with each bad parameter in
+// the details.
+type BindError struct {
+	Params []ParamError
+}
+
+func (e *BindError) Error() string {
+	msgs := make([]string, 0, len(e.Params))
+	for _, p := range e.Params {
+		msgs = append(msgs, p.In+" "+p.Name+": "+p.Message)
+	}
+	return "bind parameters: " + strings.Join(msgs, "; ")
+}
+
+// StatusCode returns the HTTP status code for the error.
+func (e *BindError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// HTTPError converts the error to an HTTPError, with each bad parameter in the details.
+func (e *BindError) HTTPError() *HTTPError {
+	details := make([]any, 0, len(e.Params))
+	for _, p := range e.Params {
+		details = append(details, p)
+	}
+
+	return NewHTTPError(http.StatusBadRequest, errors.New("invalid parameters"), details...)
+}
+
+// bindField is a struct field bound from a request parameter.
+type bindField struct {
+	index []int
+	in    string
+	name  string
+	// defaultValue is the value of the default tag. It is empty when the field has no default.
+	defaultValue string
+}
+
+// Bind creates a T and fills its fields from the request parameters named by their struct tags:
+//
+//	type ListUsersParams struct {
+//		OrgID   string         `path:"org_id"`
+//		Limit   int            `query:"limit" default:"20"`
+//		Roles   []string       `query:"role"`
+//		Since   *time.Time     `query:"since"`
+//		Timeout time.Duration  `header:"X-Timeout" default:"5s"`
+//		Session string         `cookie:"session"`
+//	}
+//
+// Path variables are read from gorilla mux. Fields may be strings, booleans, numbers, time.Time (RFC 3339),
+// time.Duration, types implementing encoding.TextUnmarshaler, pointers to any of these, which are left nil when the
+// parameter is missing, and slices of any of these, which are filled from repeated parameters. Missing parameters take
+// the value of the `default` tag if there is one, which for a slice is a comma separated list. Embedded structs are
+// bound too.
+//
+// Every parameter that cannot be converted is reported in a BindError, which DecodeErrorHandler writes as a 400.
+func Bind[T any](r *http.Request) (*T, error) {
+	v := new(T)
+
+	rv := reflect.ValueOf(v).Elem()
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("bind parameters: %T is not a struct", *v)
+	}
+
+	var vars map[string]string
+	paramErrs := bindValues(rv, bindFieldsOf(rv.Type(), bindSources), func(field bindField) []string {
+		switch field.in {
+		case bindSourcePath:
+			if vars == nil {
+				vars = mux.Vars(r)
+			}
+			if value, ok := vars[field.name]; ok {
+				return []string{value}
+			}
+		case bindSourceQuery:
+			return r.URL.Query()[field.name]
+		case bindSourceHeader:
+			return r.Header.Values(field.name)
+		case bindSourceCookie:
+			if cookie, err := r.Cookie(field.name); err == nil {
+				return []string{cookie.Value}
+			}
+		}
+		return nil
+	})
+
+	if len(paramErrs) > 0 {
+		return nil, &BindError{Params: paramErrs}
+	}
+
+	return v, nil
+}
+
+// bindValues sets each field to the values lookup returns for it, or to its default when there are none, and returns
+// the fields that could not be converted.
+func bindValues(rv reflect.Value, fields []bindField, lookup func(field bindField) []string) []ParamError {
+	paramErrs := make([]ParamError, 0)
+	for _, field := range fields {
+		fv := rv.FieldByIndex(field.index)
+
+		values := lookup(field)
+		if len(values) == 0 {
+			if field.defaultValue == "" {
+				continue
+			}
+			values = defaultValues(fv, field.defaultValue)
+		}
+
+		if err := setField(fv, values); err != nil {
+			paramErrs = append(paramErrs, ParamError{
+				In:      field.in,
+				Name:    field.name,
+				Message: err.Error(),
+			})
+		}
+	}
+	return paramErrs
+}
+
+// defaultValues returns the values of the default for the field. The default of a slice holds its values separated by
+// commas, while the default of any other field is a single value.
+func defaultValues(field reflect.Value, defaultValue string) []string {
+	if isSliceField(field) {
+		return strings.Split(defaultValue, ",")
+	}
+	return []string{defaultValue}
+}
+
+// bindFieldsOf returns the fields of the struct type that are bound from one of the sources.
+func bindFieldsOf(t reflect.Type, sources []string) []bindField {
+	key := bindFieldsKey{
+		typ:     t,
+		sources: strings.Join(sources, ","),
+	}
+
+	if cached, ok := bindFieldsCache.Load(key); ok {
+		return cached.([]bindField) // nolint:forcetypeassert // The cache only holds []bindField
+	}
+
+	fields := collectBindFields(t, sources, nil)
+	bindFieldsCache.Store(key, fields)
+	return fields
+}
+
+func collectBindFields(t reflect.Type, sources []string, index []int) []bindField {
+	fields := make([]bindField, 0)
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		fieldIndex := append(append(make([]int, 0, len(index)+1), index...), i)
+
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			fields = append(fields, collectBindFields(sf.Type, sources, fieldIndex)...)
+			continue
+		}
+
+		if !sf.IsExported() {
+			continue
+		}
+
+		for _, in := range sources {
+			name, ok := sf.Tag.Lookup(in)
+			if !ok || name == "" || name == "-" {
+				continue
+			}
+
+			fields = append(fields, bindField{
+				index:        fieldIndex,
+				in:           in,
+				name:         name,
+				defaultValue: sf.Tag.Get(bindTagDefault),
+			})
+			break
+		}
+	}
+	return fields
+}
+
+// setField converts the values to the type of the field and sets it. Fields that are not slices take the first value.
+func setField(field reflect.Value, values []string) error {
+	if isSliceField(field) {
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setValue(slice.Index(i), value); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+
+	return setValue(field, values[0])
+}
+
+// isSliceField reports whether the field is bound from every value of its parameter. Slices implementing
+// encoding.TextUnmarshaler are bound from a single value, like net.IP.
+func isSliceField(field reflect.Value) bool {
+	return field.Kind() == reflect.Slice && !reflect.PointerTo(field.Type()).Implements(textUnmarshalerType)
+}
+
+// setValue converts a single value to the type of the field and sets it.
+func setValue(field reflect.Value, value string) error {
+	if field.Kind() == reflect.Pointer {
+		elem := reflect.New(field.Type().Elem())
+		if err := setValue(elem.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(elem)
+		return nil
+	}
+
+	if unmarshaler, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok && field.Type() != timeType {
+		if err := unmarshaler.UnmarshalText([]byte(value)); err != nil {
+			return fmt.Errorf("invalid value %q: %w", value, err)
+		}
+		return nil
+	}
+
+	switch field.Type() {
+	case timeType:
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return fmt.Errorf("invalid time %q, expected RFC 3339", value)
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() { // nolint:exhaustive // Every other kind is unsupported
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", value)
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+
+	return nil
+}
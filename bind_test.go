@@ -0,0 +1,163 @@
+package uhttp
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+type bindPage struct {
+	Limit  int  `query:"limit" default:"20"`
+	Offset uint `query:"offset"`
+}
+
+type bindParams struct {
+	bindPage
+
+	OrgID   string        `path:"org_id"`
+	Roles   []string      `query:"role"`
+	Scores  []float64     `query:"score" default:"1.5,2"`
+	Active  *bool         `query:"active"`
+	Since   *time.Time    `query:"since"`
+	Timeout time.Duration `header:"X-Timeout" default:"5s"`
+	IP      net.IP        `header:"X-Forwarded-For"`
+	Session string        `cookie:"session"`
+	Ignored string
+}
+
+func TestBind(t *testing.T) {
+	since := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	active := true
+
+	tests := []struct {
+		name    string
+		target  string
+		vars    map[string]string
+		headers map[string]string
+		cookie  *http.Cookie
+		want    *bindParams
+	}{
+		{
+			name:   "defaults",
+			target: "/",
+			want: &bindParams{
+				bindPage: bindPage{Limit: 20},
+				Scores:   []float64{1.5, 2},
+				Timeout:  5 * time.Second,
+			},
+		},
+		{
+			name:   "all sources",
+			target: "/?limit=5&offset=10&role=admin&role=user&score=3&active=true&since=2024-01-02T03:04:05Z",
+			vars:   map[string]string{"org_id": "acme"},
+			headers: map[string]string{
+				"X-Timeout":       "1m",
+				"X-Forwarded-For": "10.0.0.1",
+			},
+			cookie: &http.Cookie{Name: "session", Value: "abc"},
+			want: &bindParams{
+				bindPage: bindPage{Limit: 5, Offset: 10},
+				OrgID:    "acme",
+				Roles:    []string{"admin", "user"},
+				Scores:   []float64{3},
+				Active:   &active,
+				Since:    &since,
+				Timeout:  time.Minute,
+				IP:       net.ParseIP("10.0.0.1"),
+				Session:  "abc",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, http.NoBody)
+			if tt.vars != nil {
+				r = mux.SetURLVars(r, tt.vars)
+			}
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			if tt.cookie != nil {
+				r.AddCookie(tt.cookie)
+			}
+
+			got, err := Bind[bindParams](r)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// bindRaw is a slice bound from a single value, which it keeps as is.
+type bindRaw []byte
+
+func (b *bindRaw) UnmarshalText(text []byte) error {
+	*b = append((*b)[:0], text...)
+	return nil
+}
+
+func TestBind_Defaults(t *testing.T) {
+	type params struct {
+		Sort   string   `query:"sort" default:"name,asc"`
+		Filter bindRaw  `query:"filter" default:"a,b"`
+		Tags   []string `query:"tag" default:"a,b"`
+		Limits []int    `query:"limit" default:""`
+		Name   *string  `query:"name" default:""`
+	}
+
+	// Only the default of a slice is split on commas, and an empty default sets nothing.
+	got, err := Bind[params](httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+	require.NoError(t, err)
+	require.Equal(t, &params{
+		Sort:   "name,asc",
+		Filter: bindRaw("a,b"),
+		Tags:   []string{"a", "b"},
+	}, got)
+}
+
+func TestBind_Errors(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/?limit=ten&offset=-1&score=1&score=x&active=maybe&since=yesterday", http.NoBody)
+	r.Header.Set("X-Timeout", "soon")
+	r.Header.Set("X-Forwarded-For", "not-an-ip")
+	r.Header.Set(requestIDHeader, "123")
+	r = r.WithContext(RequestIDToContext(r.Context(), r))
+
+	got, err := Bind[bindParams](r)
+	require.Nil(t, got)
+
+	bindErr := new(BindError)
+	require.ErrorAs(t, err, &bindErr)
+	require.Equal(t, []ParamError{
+		{In: "query", Name: "limit", Message: `invalid integer "ten"`},
+		{In: "query", Name: "offset", Message: `invalid unsigned integer "-1"`},
+		{In: "query", Name: "score", Message: `invalid number "x"`},
+		{In: "query", Name: "active", Message: `invalid boolean "maybe"`},
+		{In: "query", Name: "since", Message: `invalid time "yesterday", expected RFC 3339`},
+		{In: "header", Name: "X-Timeout", Message: `invalid duration "soon"`},
+		{In: "header", Name: "X-Forwarded-For", Message: `invalid value "not-an-ip": invalid IP address: not-an-ip`},
+	}, bindErr.Params)
+
+	w := httptest.NewRecorder()
+	DecodeErrorHandler(w, r, err)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	resp := new(HTTPError)
+	require.NoError(t, DecodeJSON(w.Result().Body, resp))
+	require.Equal(t, "invalid parameters", resp.Detail)
+	require.Equal(t, "123", resp.RequestId)
+	require.Len(t, resp.Details, 7)
+	require.Equal(t, map[string]any{"in": "query", "name": "limit", "message": `invalid integer "ten"`}, resp.Details[0])
+}
+
+func TestBind_NotAStruct(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+
+	_, err := Bind[[]string](r)
+	require.EqualError(t, err, "bind parameters: []string is not a struct")
+}
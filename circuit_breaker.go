@@ -0,0 +1,141 @@
+package uhttp
+
+import (
+	"sync"
+	"time"
+)
+
+type circuitState int
+
+const (
+	// circuitClosed lets every call through.
+	circuitClosed circuitState = iota
+
+	// circuitOpen rejects every call until the cooldown has passed.
+	circuitOpen
+
+	// circuitHalfOpen lets a single probe call through to test whether the dependency has recovered.
+	circuitHalfOpen
+)
+
+// String returns the name of the state for logging.
+func (s circuitState) String() string {
+	switch s {
+	case circuitClosed:
+		return "closed"
+	case circuitOpen:
+		return "open"
+	case circuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// circuitBreaker stops calls to a failing dependency after a number of consecutive failures, and periodically probes
+// it to detect when it has recovered. A nil circuitBreaker lets every call through.
+type circuitBreaker struct {
+	mu sync.Mutex
+
+	state    circuitState
+	failures int
+	openedAt time.Time
+
+	// threshold is the number of consecutive failures that open the circuit.
+	threshold int
+
+	// cooldown is how long the circuit stays open before a probe is let through.
+	cooldown time.Duration
+
+	// onStateChange is called with the lock held whenever the state changes.
+	onStateChange func(from, to circuitState)
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration, onStateChange func(from, to circuitState)) *circuitBreaker {
+	return &circuitBreaker{
+		threshold:     max(threshold, 1),
+		cooldown:      cooldown,
+		onStateChange: onStateChange,
+	}
+}
+
+// allow returns true if the call should be made.
+func (b *circuitBreaker) allow(now time.Time) bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if now.Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(circuitHalfOpen)
+		return true
+	case circuitHalfOpen:
+		// A probe is already in flight.
+		return false
+	default:
+		return true
+	}
+}
+
+// success records a successful call, closing the circuit.
+func (b *circuitBreaker) success() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.setState(circuitClosed)
+}
+
+// failure records a failed call, opening the circuit if the probe failed or the threshold has been reached.
+func (b *circuitBreaker) failure(now time.Time) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == circuitHalfOpen || b.failures >= b.threshold {
+		b.openedAt = now
+		b.setState(circuitOpen)
+	}
+}
+
+// abandon records a call the caller gave up on, which says nothing about the health of the dependency. An abandoned
+// probe opens the circuit again without restarting the cooldown, so that the next call is let through as a new probe.
+func (b *circuitBreaker) abandon() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == circuitHalfOpen {
+		b.setState(circuitOpen)
+	}
+}
+
+func (b *circuitBreaker) setState(state circuitState) {
+	if b.state == state {
+		return
+	}
+
+	from := b.state
+	b.state = state
+
+	if b.onStateChange != nil {
+		b.onStateChange(from, state)
+	}
+}
@@ -0,0 +1,78 @@
+package uhttp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var transitions []string
+	b := newCircuitBreaker(2, time.Minute, func(from, to circuitState) {
+		transitions = append(transitions, from.String()+"->"+to.String())
+	})
+
+	now := time.Now()
+
+	require.True(t, b.allow(now))
+	b.failure(now)
+	require.True(t, b.allow(now))
+
+	// A success resets the consecutive failures.
+	b.success()
+	b.failure(now)
+	require.True(t, b.allow(now))
+
+	b.failure(now)
+	require.False(t, b.allow(now))
+	require.False(t, b.allow(now.Add(59*time.Second)))
+
+	// After the cooldown a single probe is let through.
+	require.True(t, b.allow(now.Add(time.Minute)))
+	require.False(t, b.allow(now.Add(time.Minute)))
+
+	// A failed probe opens the circuit again.
+	b.failure(now.Add(time.Minute))
+	require.False(t, b.allow(now.Add(time.Minute+time.Second)))
+
+	// A successful probe closes it.
+	require.True(t, b.allow(now.Add(2*time.Minute)))
+	b.success()
+	require.True(t, b.allow(now.Add(2*time.Minute)))
+
+	require.Equal(t, []string{
+		"closed->open",
+		"open->half-open",
+		"half-open->open",
+		"open->half-open",
+		"half-open->closed",
+	}, transitions)
+}
+
+func TestCircuitBreaker_Abandon(t *testing.T) {
+	b := newCircuitBreaker(1, time.Minute, nil)
+	now := time.Now()
+
+	// Abandoning a call while closed changes nothing.
+	require.True(t, b.allow(now))
+	b.abandon()
+	require.True(t, b.allow(now))
+
+	b.failure(now)
+	require.True(t, b.allow(now.Add(time.Minute)))
+
+	// An abandoned probe lets the next call probe again, without waiting for another cooldown.
+	b.abandon()
+	require.True(t, b.allow(now.Add(time.Minute)))
+	require.False(t, b.allow(now.Add(time.Minute)))
+}
+
+func TestCircuitBreaker_Nil(t *testing.T) {
+	var b *circuitBreaker
+
+	b.failure(time.Now())
+	b.success()
+	b.abandon()
+	require.True(t, b.allow(time.Now()))
+}
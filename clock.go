@@ -0,0 +1,17 @@
+package uhttp
+
+import "time"
+
+// Clock tells the time. It lets the rate limiters and the ResponseWriter be driven by a fake clock in tests.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+}
+
+// systemClock is the Clock backed by the system time.
+type systemClock struct{}
+
+// Now returns the current system time.
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
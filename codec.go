@@ -0,0 +1,255 @@
+package uhttp
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	// jsonStructTag is the struct tag MessagePack reads field names from, so that values have the same keys as JSON.
+	jsonStructTag = "json"
+)
+
+// Codec encodes and decodes values in a media type.
+type Codec interface {
+	// Encode writes v to the writer.
+	Encode(w io.Writer, v any) error
+
+	// Decode reads a value from the reader into v, which must be a pointer.
+	Decode(r io.Reader, v any) error
+}
+
+var (
+	codecsMu sync.RWMutex
+
+	// codecs holds the codec used to decode request bodies of each media type. JSON is decoded by the JSON decoder,
+	// which supports the DecodeOptions.
+	codecs = map[string]Codec{
+		ContentTypeXML:      NewXMLCodec(),
+		ContentTypeMsgpack:  NewMsgpackCodec(),
+		ContentTypeCBOR:     NewCBORCodec(),
+		ContentTypeProtobuf: NewProtobufCodec(),
+	}
+)
+
+// RegisterCodec sets the codec used by DecodeRequest for request bodies of the media type, and registers it with
+// RegisterEncoder so that EncodeNegotiated can write the media type.
+func RegisterCodec(mediaType string, codec Codec) {
+	mediaType = strings.ToLower(mediaType)
+
+	codecsMu.Lock()
+	codecs[mediaType] = codec
+	codecsMu.Unlock()
+
+	RegisterEncoder(mediaType, NewCodecEncoder(mediaType, codec))
+}
+
+// codecFor returns the codec registered for the media type.
+func codecFor(mediaType string) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	codec, ok := codecs[strings.ToLower(mediaType)]
+	return codec, ok
+}
+
+// NewCodecEncoder creates an Encoder that writes values with the codec, sending contentType as the Content-Type.
+func NewCodecEncoder(contentType string, codec Codec) Encoder {
+	return EncoderFunc(func(w http.ResponseWriter, _ *http.Request, status int, v any) error {
+		w.Header().Set(HeaderContentType, contentType)
+		w.WriteHeader(status)
+		return codec.Encode(w, v)
+	})
+}
+
+// DecodeRequest decodes the body of the request into v with the codec registered for its Content-Type. JSON, and
+// requests without a Content-Type, are decoded as by DecodeRequestJSON. Other media types are rejected with a 415.
+//
+// The Content-Type and size options apply to every codec; the other options only apply to JSON.
+func DecodeRequest[T any](r *http.Request, v *T, opts ...DecodeOption) error {
+	d := newDecoder(opts...)
+
+	header := r.Header.Get(HeaderContentType)
+	if err := d.checkContentType(header); err != nil {
+		return err
+	}
+
+	if header == "" {
+		return d.decodeJSON(r.Body, v)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err == nil && strings.EqualFold(mediaType, ContentTypeJSON) {
+		return d.decodeJSON(r.Body, v)
+	}
+
+	codec, ok := codecFor(mediaType)
+	if err != nil || !ok {
+		return &DecodeError{
+			Status: http.StatusUnsupportedMediaType,
+			Err:    fmt.Errorf("%w: %q", errUnsupportedMediaType, header),
+		}
+	}
+
+	var body io.Reader = r.Body
+	if d.maxBytes > 0 {
+		body = http.MaxBytesReader(nil, r.Body, d.maxBytes)
+	}
+
+	if err := codec.Decode(body, v); err != nil {
+		return newDecodeError(err)
+	}
+	return nil
+}
+
+// xmlCodec encodes values as XML.
+type xmlCodec struct{}
+
+// NewXMLCodec creates a Codec for XML, using encoding/xml. Encoded documents start with the XML header.
+func NewXMLCodec() Codec {
+	return xmlCodec{}
+}
+
+func (xmlCodec) Encode(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(v)
+}
+
+func (xmlCodec) Decode(r io.Reader, v any) error {
+	return xml.NewDecoder(r).Decode(v)
+}
+
+// msgpackCodec encodes values as MessagePack.
+type msgpackCodec struct{}
+
+// NewMsgpackCodec creates a Codec for MessagePack. Struct fields are named by their `json` tags, so values have the
+// same keys as they do in JSON.
+func NewMsgpackCodec() Codec {
+	return msgpackCodec{}
+}
+
+func (msgpackCodec) Encode(w io.Writer, v any) error {
+	enc := msgpack.NewEncoder(w)
+	enc.SetCustomStructTag(jsonStructTag)
+	return enc.Encode(v)
+}
+
+func (msgpackCodec) Decode(r io.Reader, v any) error {
+	dec := msgpack.NewDecoder(r)
+	dec.SetCustomStructTag(jsonStructTag)
+	return dec.Decode(v)
+}
+
+// cborCodec encodes values as CBOR.
+type cborCodec struct{}
+
+// NewCBORCodec creates a Codec for CBOR (RFC 8949). Struct fields are named by their `cbor` tags, falling back to
+// their `json` tags.
+func NewCBORCodec() Codec {
+	return cborCodec{}
+}
+
+func (cborCodec) Encode(w io.Writer, v any) error {
+	return cbor.NewEncoder(w).Encode(v)
+}
+
+func (cborCodec) Decode(r io.Reader, v any) error {
+	return cbor.NewDecoder(r).Decode(v)
+}
+
+// protobufCodec encodes values as Protocol Buffers.
+type protobufCodec struct{}
+
+// NewProtobufCodec creates a Codec for Protocol Buffers. Values that are a proto.Message are encoded as themselves.
+// Any other value, such as an HTTPError, is converted through JSON to a google.protobuf.Value, so that clients can
+// still read it.
+func NewProtobufCodec() Codec {
+	return protobufCodec{}
+}
+
+func (protobufCodec) Encode(w io.Writer, v any) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		value, err := toProtoValue(v)
+		if err != nil {
+			return err
+		}
+		msg = value
+	}
+
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal protobuf: %w", err)
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+func (protobufCodec) Decode(r io.Reader, v any) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	if msg, ok := v.(proto.Message); ok {
+		return proto.Unmarshal(data, msg)
+	}
+
+	value := new(structpb.Value)
+	if err := proto.Unmarshal(data, value); err != nil {
+		return err
+	}
+	return fromProtoValue(value, v)
+}
+
+// toProtoValue converts v to a google.protobuf.Value through its JSON form.
+func toProtoValue(v any) (*structpb.Value, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+
+	return structpb.NewValue(generic)
+}
+
+// fromProtoValue converts a google.protobuf.Value into v through its JSON form.
+func fromProtoValue(value *structpb.Value, v any) error {
+	data, err := json.Marshal(value.AsInterface())
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// encodeError writes the error in the media type the request accepts, falling back to JSON when none of the
+// registered media types are acceptable, and logs an error if it fails.
+func encodeError(w http.ResponseWriter, r *http.Request, msg *HTTPError) {
+	_, encoder, ok := defaultEncoders.Negotiate(r.Header.Get(HeaderAccept))
+	if !ok {
+		encoder = defaultJSONEncoder
+	}
+
+	if err := encoder.Encode(w, r, msg.Status, msg); err != nil {
+		slog.Error("Error encoding response", slog.String(loggingKeyError, err.Error()))
+	}
+}
@@ -0,0 +1,190 @@
+package uhttp
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type codecItem struct {
+	XMLName xml.Name `json:"-" xml:"item"`
+	Name    string   `json:"name" xml:"name"`
+	Count   int      `json:"count" xml:"count"`
+	Tags    []string `json:"tags" xml:"tag"`
+}
+
+func TestCodecs_RoundTrip(t *testing.T) {
+	mediaTypes := []string{
+		ContentTypeJSON,
+		ContentTypeXML,
+		ContentTypeMsgpack,
+		ContentTypeCBOR,
+		ContentTypeProtobuf,
+	}
+
+	for _, mediaType := range mediaTypes {
+		t.Run(mediaType, func(t *testing.T) {
+			want := &codecItem{Name: "widget", Count: 3, Tags: []string{"a", "b"}}
+
+			r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			r.Header.Set(HeaderAccept, mediaType)
+
+			w := httptest.NewRecorder()
+			require.NoError(t, EncodeNegotiated(w, r, http.StatusOK, want))
+			require.Equal(t, mediaType, w.Header().Get(HeaderContentType))
+
+			r = httptest.NewRequest(http.MethodPost, "/", w.Body)
+			r.Header.Set(HeaderContentType, mediaType)
+
+			got := new(codecItem)
+			require.NoError(t, DecodeRequest(r, got))
+			got.XMLName = xml.Name{}
+			require.Equal(t, want, got)
+		})
+	}
+}
+
+func TestProtobufCodec_Message(t *testing.T) {
+	want := timestamppb.New(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, NewProtobufCodec().Encode(buf, want))
+
+	got := new(timestamppb.Timestamp)
+	require.NoError(t, NewProtobufCodec().Decode(buf, got))
+	require.True(t, want.AsTime().Equal(got.AsTime()))
+}
+
+func TestDecodeRequest_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		opts        []DecodeOption
+		wantStatus  int
+	}{
+		{
+			name:        "unsupported",
+			contentType: "text/csv",
+			body:        "a,b",
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "malformed",
+			contentType: ContentTypeXML,
+			body:        "<item><name>",
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "too large",
+			contentType: ContentTypeXML,
+			body:        "<item><name>" + strings.Repeat("a", 100) + "</name></item>",
+			opts:        []DecodeOption{WithMaxBodyBytes(32)},
+			wantStatus:  http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:        "required content type",
+			contentType: ContentTypeCBOR,
+			opts:        []DecodeOption{WithRequiredContentType(ContentTypeJSON, ContentTypeXML)},
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			r.Header.Set(HeaderContentType, tt.contentType)
+
+			err := DecodeRequest(r, new(codecItem), tt.opts...)
+
+			decodeErr := new(DecodeError)
+			require.ErrorAs(t, err, &decodeErr)
+			require.Equal(t, tt.wantStatus, decodeErr.StatusCode())
+		})
+	}
+}
+
+func TestErrorHandlers_Negotiated(t *testing.T) {
+	tests := []struct {
+		name            string
+		accept          string
+		wantContentType string
+	}{
+		{
+			name:            "default",
+			wantContentType: ContentTypeJSON,
+		},
+		{
+			name:            "xml",
+			accept:          "application/xml",
+			wantContentType: ContentTypeXML,
+		},
+		{
+			name:            "msgpack",
+			accept:          "application/msgpack",
+			wantContentType: ContentTypeMsgpack,
+		},
+		{
+			name:            "cbor",
+			accept:          "application/cbor",
+			wantContentType: ContentTypeCBOR,
+		},
+		{
+			name:            "protobuf",
+			accept:          "application/x-protobuf",
+			wantContentType: ContentTypeProtobuf,
+		},
+		{
+			name:            "not acceptable",
+			accept:          "image/png",
+			wantContentType: ContentTypeJSON,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/missing", http.NoBody)
+			r.Header.Set(HeaderAccept, tt.accept)
+			r.Header.Set(requestIDHeader, "123")
+			r = r.WithContext(RequestIDToContext(r.Context(), r))
+
+			w := httptest.NewRecorder()
+			NotFoundHandler().ServeHTTP(w, r)
+			require.Equal(t, http.StatusNotFound, w.Code)
+			require.Equal(t, tt.wantContentType, w.Header().Get(HeaderContentType))
+
+			if tt.wantContentType == ContentTypeXML {
+				var resp struct {
+					Status    int      `xml:"status"`
+					Detail    string   `xml:"detail"`
+					Details   []string `xml:"details>item"`
+					RequestId string   `xml:"request_id"`
+				}
+				require.NoError(t, xml.NewDecoder(w.Body).Decode(&resp))
+				require.Equal(t, http.StatusNotFound, resp.Status)
+				require.Equal(t, "not found", resp.Detail)
+				require.Equal(t, []string{"method: GET", "path: /missing"}, resp.Details)
+				require.Equal(t, "123", resp.RequestId)
+				return
+			}
+
+			resp := new(HTTPError)
+			if codec, ok := codecFor(tt.wantContentType); ok {
+				require.NoError(t, codec.Decode(w.Body, resp))
+			} else {
+				require.NoError(t, DecodeJSON(w.Result().Body, resp))
+			}
+			require.Equal(t, http.StatusNotFound, resp.Status)
+			require.Equal(t, "not found", resp.Detail)
+			require.Equal(t, []any{"method: GET", "path: /missing"}, resp.Details)
+			require.Equal(t, "123", resp.RequestId)
+		})
+	}
+}
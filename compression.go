@@ -0,0 +1,420 @@
+package uhttp
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// EncodingGzip is the gzip content coding.
+	EncodingGzip = "gzip"
+
+	// EncodingDeflate is the deflate content coding, which is the zlib format.
+	EncodingDeflate = "deflate"
+
+	// EncodingBrotli is the Brotli content coding.
+	EncodingBrotli = "br"
+
+	// EncodingZstd is the Zstandard content coding.
+	EncodingZstd = "zstd"
+
+	// encodingWildcard matches any content coding in an Accept-Encoding header.
+	encodingWildcard = "*"
+
+	// defaultCompressionMinSize is the default size below which responses are not compressed.
+	defaultCompressionMinSize = 1024
+
+	// brotliLevel is the Brotli quality used, trading some compression for the speed needed by dynamic responses.
+	brotliLevel = 5
+
+	headerContentRange = "Content-Range"
+	headerUpgrade      = "Upgrade"
+)
+
+// defaultCompressibleTypes are the media ranges compressed by default.
+var defaultCompressibleTypes = []string{
+	"text/html",
+	"text/plain",
+	"text/css",
+	"text/csv",
+	"text/xml",
+	"text/javascript",
+	ContentTypeJSON,
+	ContentTypeNDJSON,
+	ContentTypeXML,
+	"application/javascript",
+	"application/problem+json",
+	"application/problem+xml",
+	"image/svg+xml",
+}
+
+// compressor is a pooled encoder of a content coding.
+type compressor interface {
+	io.WriteCloser
+
+	// Flush writes any buffered data to the underlying writer.
+	Flush() error
+
+	// Reset discards the state of the encoder, so that it writes to w as if it was new.
+	Reset(w io.Writer)
+}
+
+// compressorPools holds the encoders of each content coding for reuse, as they are expensive to create.
+var compressorPools = map[string]*sync.Pool{
+	EncodingGzip: {New: func() any {
+		return gzip.NewWriter(nil)
+	}},
+	EncodingDeflate: {New: func() any {
+		return zlib.NewWriter(nil)
+	}},
+	EncodingBrotli: {New: func() any {
+		return brotli.NewWriterLevel(nil, brotliLevel)
+	}},
+	EncodingZstd: {New: func() any {
+		enc, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1)) // nolint:errcheck // Only fails on invalid options
+		return enc
+	}},
+}
+
+// CompressionOption configures the compression middleware.
+type CompressionOption func(*compression)
+
+// compression holds the configuration of the compression middleware.
+type compression struct {
+	// encodings are the content codings offered, most preferred first.
+	encodings []string
+
+	// minSize is the size below which responses are not compressed.
+	minSize int
+
+	// types are the media ranges of the content types that are compressed.
+	types []mediaRange
+}
+
+// WithCompressionEncodings sets the content codings offered, most preferred first. Content codings other than gzip,
+// deflate, br and zstd are ignored. The default is zstd, br, gzip and deflate.
+func WithCompressionEncodings(encodings ...string) CompressionOption {
+	return func(c *compression) {
+		c.encodings = make([]string, 0, len(encodings))
+		for _, encoding := range encodings {
+			encoding = strings.ToLower(encoding)
+			if _, ok := compressorPools[encoding]; ok {
+				c.encodings = append(c.encodings, encoding)
+			}
+		}
+	}
+}
+
+// WithCompressionMinSize sets the size in bytes below which responses are not compressed, as the saving would not be
+// worth the cost. The default is 1024.
+func WithCompressionMinSize(size int) CompressionOption {
+	return func(c *compression) {
+		c.minSize = size
+	}
+}
+
+// WithCompressibleTypes sets the media ranges of the content types that are compressed, for example "text/*" or
+// "application/json". The defaults are the common text formats, JSON and XML.
+func WithCompressibleTypes(mediaRanges ...string) CompressionOption {
+	return func(c *compression) {
+		c.types = parseAccept(strings.Join(mediaRanges, ","))
+	}
+}
+
+// Compress returns a middleware that compresses responses in the content coding that best satisfies the
+// Accept-Encoding header of the request. Responses are only compressed when they are at least the minimum size and
+// their content type is compressible, and never when they already have a Content-Encoding. Vary: Accept-Encoding is
+// set on every response, as the response depends on it.
+//
+// When the response writer passed to the middleware is or wraps a *ResponseWriter, its BytesWritten reports the
+// compressed size and its UncompressedBytesWritten the size before compression. InstrumentCompressedResponseSize
+// observes both when it wraps this middleware. InstrumentResponseSize only observes the compressed size when it wraps
+// this middleware, and the uncompressed size when it is wrapped by it.
+func Compress(opts ...CompressionOption) MiddlewareFunc {
+	c := &compression{
+		encodings: []string{EncodingZstd, EncodingBrotli, EncodingGzip, EncodingDeflate},
+		minSize:   defaultCompressionMinSize,
+		types:     parseAccept(strings.Join(defaultCompressibleTypes, ",")),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			addVary(w.Header(), HeaderAcceptEncoding)
+
+			encoding := negotiateEncoding(r.Header.Get(HeaderAcceptEncoding), c.encodings)
+			if encoding == "" || r.Method == http.MethodHead || r.Header.Get(headerUpgrade) != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				c:              c,
+				rw:             responseWriterOf(w),
+				encoding:       encoding,
+			}
+
+			// The encoder goes back to its pool even if the handler panics.
+			defer cw.close()
+
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// negotiateEncoding returns the offered content coding that best satisfies the Accept-Encoding header, or an empty
+// string if the response should not be compressed. The content coding with the highest quality wins, and ties go to
+// the one offered first.
+func negotiateEncoding(acceptEncoding string, offered []string) string {
+	if strings.TrimSpace(acceptEncoding) == "" {
+		return ""
+	}
+
+	qualities := make(map[string]float64)
+	for part := range strings.SplitSeq(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+
+		quality := 1.0
+		if name, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.EqualFold(strings.TrimSpace(name), "q") {
+			q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || q < 0 || q > 1 {
+				continue
+			}
+			quality = q
+		}
+		qualities[coding] = quality
+	}
+
+	best, bestQuality := "", 0.0
+	for _, encoding := range offered {
+		quality, ok := qualities[encoding]
+		if !ok {
+			quality = qualities[encodingWildcard]
+		}
+
+		if quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+	return best
+}
+
+// addVary adds the header name to the Vary header, unless it is already listed.
+func addVary(h http.Header, name string) {
+	for _, value := range h.Values(HeaderVary) {
+		for field := range strings.SplitSeq(value, ",") {
+			field = strings.TrimSpace(field)
+			if field == "*" || strings.EqualFold(field, name) {
+				return
+			}
+		}
+	}
+	h.Add(HeaderVary, name)
+}
+
+// compressWriter compresses the response in a content coding. The start of the response is held back until there is
+// enough of it to decide whether it is worth compressing.
+type compressWriter struct {
+	http.ResponseWriter
+	c *compression
+
+	// rw is the *ResponseWriter the response writer passed to the middleware is or wraps, if any, which is told the
+	// uncompressed size of the response.
+	rw *ResponseWriter
+
+	// encoding is the negotiated content coding.
+	encoding string
+
+	// status is the status code written by the handler, or zero if it has not written one.
+	status int
+
+	// buf holds the start of the response until it is decided whether to compress it.
+	buf []byte
+
+	// decided is true once the header has been written, with or without compression.
+	decided bool
+
+	// enc is the encoder, or nil if the response is not compressed.
+	enc compressor
+}
+
+// WriteHeader holds the status code until it is decided whether to compress the response. Informational responses
+// are sent straight away.
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.decided || cw.status != 0 {
+		return
+	}
+
+	if code < http.StatusOK {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	cw.status = code
+	if !bodyAllowed(code) {
+		cw.decide(false)
+	}
+}
+
+// Write compresses the data, holding it back until the response reaches the minimum size.
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if !cw.decided {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+
+		cw.buf = append(cw.buf, p...)
+		if len(cw.buf) >= cw.c.minSize {
+			if err := cw.decide(cw.compressible()); err != nil {
+				return 0, err
+			}
+		}
+		return len(p), nil
+	}
+
+	return cw.write(p)
+}
+
+// Flush sends what has been written to the client. A response flushed before it reaches the minimum size is
+// compressed if its content type is compressible, as it is being streamed.
+func (cw *compressWriter) Flush() {
+	_ = cw.FlushError()
+}
+
+// FlushError is Flush, returning an error that wraps http.ErrNotSupported if the underlying writer cannot flush.
+func (cw *compressWriter) FlushError() error {
+	if !cw.decided {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		if err := cw.decide(cw.compressible()); err != nil {
+			return err
+		}
+	}
+
+	if cw.enc != nil {
+		if err := cw.enc.Flush(); err != nil {
+			return err
+		}
+	}
+	return http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying http.ResponseWriter, so that http.ResponseController can reach it.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// compressible returns true if the response held back can be compressed.
+func (cw *compressWriter) compressible() bool {
+	h := cw.Header()
+	if !bodyAllowed(cw.status) || cw.status == http.StatusPartialContent ||
+		h.Get(HeaderContentEncoding) != "" || h.Get(headerContentRange) != "" {
+		return false
+	}
+
+	contentType := h.Get(HeaderContentType)
+	if contentType == "" && cw.rw != nil {
+		cw.rw.writeDefaultHeaders()
+		contentType = h.Get(HeaderContentType)
+	}
+	if contentType == "" {
+		// The content type cannot be sniffed from the compressed response, so it is sniffed here.
+		contentType = http.DetectContentType(cw.buf)
+		h.Set(HeaderContentType, contentType)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	quality, _ := matchMediaRange(cw.c.types, mediaType)
+	return quality > 0
+}
+
+// decide writes the header, compressing the rest of the response if compress is true, and then writes the response
+// held back.
+func (cw *compressWriter) decide(compress bool) error {
+	cw.decided = true
+
+	if compress {
+		h := cw.Header()
+		h.Set(HeaderContentEncoding, cw.encoding)
+		h.Del(HeaderContentLength)
+
+		cw.enc = compressorPools[cw.encoding].Get().(compressor) // nolint:forcetypeassert // The pools only hold compressors
+		cw.enc.Reset(cw.ResponseWriter)
+
+		if cw.rw != nil {
+			cw.rw.addUncompressedBytes(0)
+		}
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+
+	_, err := cw.write(buf)
+	return err
+}
+
+// write writes the data once it has been decided whether to compress the response.
+func (cw *compressWriter) write(p []byte) (int, error) {
+	if cw.enc == nil {
+		return cw.ResponseWriter.Write(p)
+	}
+
+	n, err := cw.enc.Write(p)
+	if cw.rw != nil {
+		cw.rw.addUncompressedBytes(n)
+	}
+	return n, err
+}
+
+// close finishes the response, writing it uncompressed if it never reached the minimum size, and returns the encoder
+// to its pool.
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		if cw.status == 0 && len(cw.buf) == 0 {
+			// Nothing was written, so the server writes the response as it would without the middleware.
+			return
+		}
+		_ = cw.decide(false)
+	}
+
+	if cw.enc == nil {
+		return
+	}
+
+	_ = cw.enc.Close()
+	cw.enc.Reset(io.Discard)
+	compressorPools[cw.encoding].Put(cw.enc)
+	cw.enc = nil
+}
+
+// bodyAllowed returns true if a response with the status code can have a body.
+func bodyAllowed(status int) bool {
+	return status >= http.StatusOK && status != http.StatusNoContent && status != http.StatusNotModified
+}
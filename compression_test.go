@@ -0,0 +1,354 @@
+package uhttp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/require"
+)
+
+// decompress decodes the body in the content coding.
+func decompress(t *testing.T, encoding string, body []byte) string {
+	t.Helper()
+
+	var (
+		r   io.Reader
+		err error
+	)
+	switch encoding {
+	case EncodingGzip:
+		r, err = gzip.NewReader(bytes.NewReader(body))
+	case EncodingDeflate:
+		r, err = zlib.NewReader(bytes.NewReader(body))
+	case EncodingBrotli:
+		r = brotli.NewReader(bytes.NewReader(body))
+	case EncodingZstd:
+		var dec *zstd.Decoder
+		dec, err = zstd.NewReader(bytes.NewReader(body))
+		if err == nil {
+			defer dec.Close()
+		}
+		r = dec
+	default:
+		return string(body)
+	}
+	require.NoError(t, err)
+
+	got, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(got)
+}
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat(`{"name":"value"}`, 200)
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		method         string
+		handler        http.HandlerFunc
+		wantEncoding   string
+		wantStatus     int
+		wantBody       string
+	}{
+		{
+			name:           "gzip",
+			acceptEncoding: "gzip",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set(HeaderContentType, ContentTypeJSON)
+				_, _ = w.Write([]byte(large))
+			},
+			wantEncoding: EncodingGzip,
+			wantStatus:   http.StatusOK,
+			wantBody:     large,
+		},
+		{
+			name:           "deflate",
+			acceptEncoding: "deflate",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set(HeaderContentType, ContentTypeJSON)
+				_, _ = w.Write([]byte(large))
+			},
+			wantEncoding: EncodingDeflate,
+			wantStatus:   http.StatusOK,
+			wantBody:     large,
+		},
+		{
+			name:           "brotli",
+			acceptEncoding: "br",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set(HeaderContentType, ContentTypeJSON)
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte(large))
+			},
+			wantEncoding: EncodingBrotli,
+			wantStatus:   http.StatusCreated,
+			wantBody:     large,
+		},
+		{
+			name:           "zstd preferred",
+			acceptEncoding: "gzip, deflate, br, zstd",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set(HeaderContentType, "text/plain; charset=utf-8")
+				// Written in small pieces to cross the threshold part way.
+				for i := 0; i < len(large); i += 100 {
+					_, _ = w.Write([]byte(large[i:min(i+100, len(large))]))
+				}
+			},
+			wantEncoding: EncodingZstd,
+			wantStatus:   http.StatusOK,
+			wantBody:     large,
+		},
+		{
+			name:           "sniffed content type",
+			acceptEncoding: "gzip",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte("<html>" + large + "</html>"))
+			},
+			wantEncoding: EncodingGzip,
+			wantStatus:   http.StatusOK,
+			wantBody:     "<html>" + large + "</html>",
+		},
+		{
+			name:           "below threshold",
+			acceptEncoding: "gzip",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set(HeaderContentType, ContentTypeJSON)
+				_, _ = w.Write([]byte(`{"small":true}`))
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"small":true}`,
+		},
+		{
+			name:           "not compressible",
+			acceptEncoding: "gzip",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set(HeaderContentType, "image/png")
+				_, _ = w.Write([]byte(large))
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   large,
+		},
+		{
+			name:           "already encoded",
+			acceptEncoding: "gzip",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set(HeaderContentType, ContentTypeJSON)
+				w.Header().Set(HeaderContentEncoding, "custom")
+				_, _ = w.Write([]byte(large))
+			},
+			wantEncoding: "custom",
+			wantStatus:   http.StatusOK,
+			wantBody:     large,
+		},
+		{
+			name:           "no content",
+			acceptEncoding: "gzip",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:           "not accepted",
+			acceptEncoding: "gzip;q=0, compress",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set(HeaderContentType, ContentTypeJSON)
+				_, _ = w.Write([]byte(large))
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   large,
+		},
+		{
+			name:           "head",
+			acceptEncoding: "gzip",
+			method:         http.MethodHead,
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set(HeaderContentType, ContentTypeJSON)
+			},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			r := httptest.NewRequest(method, "/", http.NoBody)
+			r.Header.Set(HeaderAcceptEncoding, tt.acceptEncoding)
+			w := httptest.NewRecorder()
+
+			Compress()(tt.handler).ServeHTTP(w, r)
+
+			require.Equal(t, tt.wantStatus, w.Code)
+			require.Equal(t, tt.wantEncoding, w.Header().Get(HeaderContentEncoding))
+			require.Equal(t, HeaderAcceptEncoding, w.Header().Get(HeaderVary))
+			require.Equal(t, tt.wantBody, decompress(t, tt.wantEncoding, w.Body.Bytes()))
+		})
+	}
+}
+
+func TestCompress_ResponseWriter(t *testing.T) {
+	body := strings.Repeat("compressible ", 500)
+	handler := Compress()(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set(HeaderContentType, "text/plain")
+		_, _ = w.Write([]byte(body))
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	r.Header.Set(HeaderAcceptEncoding, "gzip")
+	rec := httptest.NewRecorder()
+	rw := NewResponseWriter(rec)
+
+	handler.ServeHTTP(rw, r)
+
+	require.True(t, rw.IsCompressed())
+	require.Equal(t, uint64(len(body)), rw.UncompressedBytesWritten())
+	require.Equal(t, uint64(rec.Body.Len()), rw.BytesWritten())
+	require.Less(t, rw.BytesWritten(), rw.UncompressedBytesWritten())
+
+	// Without compression both sizes are the same.
+	r.Header.Del(HeaderAcceptEncoding)
+	rw = NewResponseWriter(httptest.NewRecorder())
+
+	handler.ServeHTTP(rw, r)
+
+	require.False(t, rw.IsCompressed())
+	require.Equal(t, uint64(len(body)), rw.BytesWritten())
+	require.Equal(t, uint64(len(body)), rw.UncompressedBytesWritten())
+}
+
+func TestCompress_WrappedResponseWriter(t *testing.T) {
+	body := strings.Repeat("compressible ", 500)
+	handler := Compress()(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set(HeaderContentType, "text/plain")
+		_, _ = w.Write([]byte(body))
+	}))
+
+	// The instrumentation wraps the writer between the *ResponseWriter and the middleware.
+	handler = promhttp.InstrumentHandlerResponseSize(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "response_size_bytes",
+	}, nil), handler)
+
+	r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	r.Header.Set(HeaderAcceptEncoding, "gzip")
+	rec := httptest.NewRecorder()
+	rw := NewResponseWriter(rec)
+
+	handler.ServeHTTP(rw, r)
+
+	require.True(t, rw.IsCompressed())
+	require.Equal(t, uint64(len(body)), rw.UncompressedBytesWritten())
+	require.Equal(t, uint64(rec.Body.Len()), rw.BytesWritten())
+}
+
+func TestCompress_Panic(t *testing.T) {
+	body := strings.Repeat("compressible ", 500)
+	handler := Compress()(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set(HeaderContentType, "text/plain")
+		_, _ = w.Write([]byte(body))
+		panic(http.ErrAbortHandler)
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	r.Header.Set(HeaderAcceptEncoding, "gzip")
+	w := httptest.NewRecorder()
+
+	require.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.ServeHTTP(w, r)
+	})
+
+	// The encoder was closed, finishing the stream, before it went back to the pool.
+	require.Equal(t, EncodingGzip, w.Header().Get(HeaderContentEncoding))
+	require.Equal(t, body, decompress(t, EncodingGzip, w.Body.Bytes()))
+}
+
+func TestCompress_ResponseWriterDefaultContentType(t *testing.T) {
+	handler := Compress()(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(strings.Repeat("[1,2,3],", 500)))
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	r.Header.Set(HeaderAcceptEncoding, "gzip")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(NewResponseWriter(rec), r)
+
+	// The default content type of the writer is used rather than sniffing the body.
+	require.Equal(t, ContentTypeJSON, rec.Header().Get(HeaderContentType))
+	require.Equal(t, EncodingGzip, rec.Header().Get(HeaderContentEncoding))
+}
+
+func TestCompress_Flush(t *testing.T) {
+	handler := Compress()(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set(HeaderContentType, ContentTypeNDJSON)
+		_, _ = w.Write([]byte("{\"id\":1}\n"))
+		require.NoError(t, http.NewResponseController(w).Flush())
+		_, _ = w.Write([]byte("{\"id\":2}\n"))
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	r.Header.Set(HeaderAcceptEncoding, "gzip")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, r)
+
+	// A flushed response is compressed whatever its size, as it is being streamed.
+	require.True(t, w.Flushed)
+	require.Equal(t, EncodingGzip, w.Header().Get(HeaderContentEncoding))
+	require.Equal(t, "{\"id\":1}\n{\"id\":2}\n", decompress(t, EncodingGzip, w.Body.Bytes()))
+}
+
+func TestCompress_Vary(t *testing.T) {
+	handler := Compress()(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	w := httptest.NewRecorder()
+	w.Header().Add(HeaderVary, "Origin, accept-encoding")
+
+	handler.ServeHTTP(w, r)
+
+	require.Equal(t, []string{"Origin, accept-encoding"}, w.Header().Values(HeaderVary))
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	offered := []string{EncodingZstd, EncodingBrotli, EncodingGzip, EncodingDeflate}
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		want           string
+	}{
+		{name: "empty", acceptEncoding: "", want: ""},
+		{name: "single", acceptEncoding: "gzip", want: EncodingGzip},
+		{name: "server preference on tie", acceptEncoding: "gzip, br", want: EncodingBrotli},
+		{name: "quality", acceptEncoding: "br;q=0.5, gzip;q=0.8", want: EncodingGzip},
+		{name: "case insensitive", acceptEncoding: "GZIP;Q=1", want: EncodingGzip},
+		{name: "wildcard", acceptEncoding: "*", want: EncodingZstd},
+		{name: "wildcard with exclusion", acceptEncoding: "*, zstd;q=0", want: EncodingBrotli},
+		{name: "identity only", acceptEncoding: "identity", want: ""},
+		{name: "refused", acceptEncoding: "gzip;q=0", want: ""},
+		{name: "malformed quality", acceptEncoding: "br;q=high, gzip", want: EncodingGzip},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, negotiateEncoding(tt.acceptEncoding, offered))
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package uhttp
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ConcurrencyLimiter limits the number of requests in flight for each key.
+type ConcurrencyLimiter interface {
+	// Acquire reserves a slot for the key. It returns true if a slot was reserved, in which case release must be
+	// called once the request has completed.
+	Acquire(ctx context.Context, key string) (release func(), ok bool, err error)
+}
+
+// concurrencyLimiter is a concurrency limiter that counts the requests in flight in memory.
+type concurrencyLimiter struct {
+	mu sync.Mutex
+
+	// inFlight is the number of requests in flight for each key. Keys are removed when they reach zero.
+	inFlight map[string]int
+
+	// limit is the maximum number of requests in flight for a key.
+	limit int
+}
+
+// NewConcurrencyLimiter creates a new concurrency limiter that allows up to limit requests in flight for each key on
+// this instance.
+func NewConcurrencyLimiter(limit int) ConcurrencyLimiter {
+	return &concurrencyLimiter{
+		inFlight: make(map[string]int),
+		limit:    limit,
+	}
+}
+
+// Acquire reserves a slot for the key.
+func (c *concurrencyLimiter) Acquire(_ context.Context, key string) (release func(), ok bool, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.inFlight[key] >= c.limit {
+		return nil, false, nil
+	}
+	c.inFlight[key]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.release(key)
+		})
+	}, true, nil
+}
+
+func (c *concurrencyLimiter) release(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.inFlight[key]--
+	if c.inFlight[key] <= 0 {
+		delete(c.inFlight, key)
+	}
+}
+
+// ConcurrencyLimitOption configures the concurrency limiting middleware.
+type ConcurrencyLimitOption func(*concurrencyLimitMiddleware)
+
+type concurrencyLimitMiddleware struct {
+	// rejectHandler answers the requests that are over the limit.
+	rejectHandler http.Handler
+
+	// retryAfter is sent in the Retry-After header of rejected requests. Zero omits the header.
+	retryAfter time.Duration
+}
+
+// WithConcurrencyRejectStatus sets the status of the response to requests that are over the limit. Only 429 and 503
+// are supported; the default is 429.
+func WithConcurrencyRejectStatus(status int) ConcurrencyLimitOption {
+	return func(m *concurrencyLimitMiddleware) {
+		if status == http.StatusServiceUnavailable {
+			m.rejectHandler = ServiceUnavailableHandler()
+			return
+		}
+		m.rejectHandler = TooManyRequestsHandler()
+	}
+}
+
+// WithConcurrencyRetryAfter sets the Retry-After sent with rejected requests. The default is one second.
+func WithConcurrencyRetryAfter(retryAfter time.Duration) ConcurrencyLimitOption {
+	return func(m *concurrencyLimitMiddleware) {
+		m.retryAfter = retryAfter
+	}
+}
+
+// ConcurrencyLimit returns a middleware that limits the number of requests in flight for the key returned from
+// keyFunc. Requests over the limit are rejected with a Retry-After header. If the limiter fails, the request is
+// rejected with a 503, as the client was not throttled.
+func ConcurrencyLimit(limiter ConcurrencyLimiter, keyFunc RateLimitKeyFunc, opts ...ConcurrencyLimitOption) MiddlewareFunc {
+	m := &concurrencyLimitMiddleware{
+		rejectHandler: TooManyRequestsHandler(),
+		retryAfter:    time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			release, ok, err := limiter.Acquire(r.Context(), keyFunc(r))
+			if err != nil {
+				slog.Error("Error acquiring concurrency slot", slog.String(loggingKeyError, err.Error()))
+				ServiceUnavailableHandler().ServeHTTP(w, r)
+				return
+			} else if !ok {
+				if m.retryAfter > 0 {
+					w.Header().Set(HeaderRetryAfter, formatDeltaSeconds(m.retryAfter))
+				}
+				m.rejectHandler.ServeHTTP(w, r)
+				return
+			}
+			defer release()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
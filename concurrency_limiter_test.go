@@ -0,0 +1,134 @@
+package uhttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrencyLimiter(t *testing.T) {
+	cl := NewConcurrencyLimiter(2)
+	ctx := context.Background()
+
+	releaseA, ok, err := cl.Acquire(ctx, "key")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	releaseB, ok, err := cl.Acquire(ctx, "key")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, ok, err = cl.Acquire(ctx, "key")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// Keys are limited independently.
+	releaseOther, ok, err := cl.Acquire(ctx, "other")
+	require.NoError(t, err)
+	require.True(t, ok)
+	releaseOther()
+
+	// Releasing more than once only frees one slot.
+	releaseA()
+	releaseA()
+
+	_, ok, err = cl.Acquire(ctx, "key")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, ok, err = cl.Acquire(ctx, "key")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	releaseB()
+	require.Len(t, cl.(*concurrencyLimiter).inFlight, 1)
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []ConcurrencyLimitOption
+		wantStatus int
+		wantRetry  string
+	}{
+		{
+			name:       "default",
+			wantStatus: http.StatusTooManyRequests,
+			wantRetry:  "1",
+		},
+		{
+			name: "service unavailable",
+			opts: []ConcurrencyLimitOption{
+				WithConcurrencyRejectStatus(http.StatusServiceUnavailable),
+				WithConcurrencyRetryAfter(5 * time.Second),
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			wantRetry:  "5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := make(chan struct{})
+			unblock := make(chan struct{})
+
+			handler := WrapHandler(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/slow" {
+					close(started)
+					<-unblock
+				}
+				w.WriteHeader(http.StatusNoContent)
+			}, ConcurrencyLimit(NewConcurrencyLimiter(1), func(*http.Request) string {
+				return "key"
+			}, tt.opts...))
+
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", http.NoBody))
+			}()
+			<-started
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+			require.Equal(t, tt.wantStatus, w.Code)
+			require.Equal(t, tt.wantRetry, w.Header().Get(HeaderRetryAfter))
+
+			resp := new(HTTPError)
+			require.NoError(t, DecodeJSON(w.Result().Body, resp))
+			require.Equal(t, tt.wantStatus, resp.Status)
+
+			close(unblock)
+			wg.Wait()
+
+			// The slot is released once the slow request completes.
+			w = httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+			require.Equal(t, http.StatusNoContent, w.Code)
+		})
+	}
+}
+
+func TestConcurrencyLimit_LimiterError(t *testing.T) {
+	limiter := NewMockConcurrencyLimiter(t)
+	limiter.On("Acquire", mock.Anything, "key").Return(nil, false, errors.New("redis unavailable"))
+
+	handler := WrapHandler(func(http.ResponseWriter, *http.Request) {
+		t.Fatal("handler should not be called")
+	}, ConcurrencyLimit(limiter, func(*http.Request) string {
+		return "key"
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	require.Empty(t, w.Header().Get(HeaderRetryAfter))
+}
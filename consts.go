@@ -4,11 +4,40 @@ const (
 	loggingKeyError = "err"
 	loggingKeyKey   = "key"
 	loggingKeyCount = "count"
+	loggingKeyFrom  = "from"
+	loggingKeyTo    = "to"
+	loggingKeyUntil = "until"
+	loggingKeyTopic = "topic"
 
 	defaultHttpErrorDetail = "An error occurred"
 
-	HeaderContentType = "Content-Type"
-	HeaderRequestID   = "X-Request-ID"
+	HeaderAccept             = "Accept"
+	HeaderAcceptEncoding     = "Accept-Encoding"
+	HeaderContentEncoding    = "Content-Encoding"
+	HeaderContentLength      = "Content-Length"
+	HeaderContentType        = "Content-Type"
+	HeaderContentTypeOptions = "X-Content-Type-Options"
+	HeaderRequestID          = "X-Request-ID"
+	HeaderStreamError        = "X-Stream-Error"
+	HeaderTrailer            = "Trailer"
+	HeaderVary               = "Vary"
 
-	ContentTypeJSON = "application/json"
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+	HeaderRetryAfter         = "Retry-After"
+
+	ContentTypeJSON     = "application/json"
+	ContentTypeJSONUTF8 = "application/json; charset=utf-8"
+	ContentTypeNDJSON   = "application/x-ndjson"
+
+	ContentTypeXML      = "application/xml"
+	ContentTypeMsgpack  = "application/msgpack"
+	ContentTypeCBOR     = "application/cbor"
+	ContentTypeProtobuf = "application/x-protobuf"
+
+	ContentTypeForm          = "application/x-www-form-urlencoded"
+	ContentTypeMultipartForm = "multipart/form-data"
+
+	contentTypeOptionsNoSniff = "nosniff"
 )
@@ -0,0 +1,223 @@
+package uhttp
+
+import (
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// mediaRangeWildcard matches any type or subtype in an Accept header.
+	mediaRangeWildcard = "*"
+)
+
+// Encoder writes a value to the response in a media type.
+type Encoder interface {
+	// Encode writes v to the response with the status code, setting the Content-Type header. The request is the one
+	// being answered, and may be nil when encoding outside a handler.
+	Encode(w http.ResponseWriter, r *http.Request, status int, v any) error
+}
+
+// EncoderFunc adapts a function to an Encoder.
+type EncoderFunc func(w http.ResponseWriter, r *http.Request, status int, v any) error
+
+// Encode calls f(w, r, status, v).
+func (f EncoderFunc) Encode(w http.ResponseWriter, r *http.Request, status int, v any) error {
+	return f(w, r, status, v)
+}
+
+// EncoderRegistry holds the encoders available for content negotiation, keyed by media type.
+type EncoderRegistry struct {
+	mu sync.RWMutex
+
+	// encoders holds the encoder for each media type.
+	encoders map[string]Encoder
+
+	// mediaTypes are the registered media types in the order they were registered. The first is the default, used
+	// when the request does not express a preference.
+	mediaTypes []string
+}
+
+// NewEncoderRegistry creates a new registry with JSON as the default media type, followed by XML, MessagePack, CBOR
+// and Protobuf.
+func NewEncoderRegistry() *EncoderRegistry {
+	reg := &EncoderRegistry{
+		encoders: make(map[string]Encoder),
+	}
+
+	reg.Register(ContentTypeJSON, NewJSONEncoder())
+	reg.Register(ContentTypeXML, NewCodecEncoder(ContentTypeXML, NewXMLCodec()))
+	reg.Register(ContentTypeMsgpack, NewCodecEncoder(ContentTypeMsgpack, NewMsgpackCodec()))
+	reg.Register(ContentTypeCBOR, NewCodecEncoder(ContentTypeCBOR, NewCBORCodec()))
+	reg.Register(ContentTypeProtobuf, NewCodecEncoder(ContentTypeProtobuf, NewProtobufCodec()))
+
+	return reg
+}
+
+// Register sets the encoder for the media type, for example "application/xml". Registering a media type again
+// replaces its encoder.
+func (reg *EncoderRegistry) Register(mediaType string, encoder Encoder) {
+	mediaType = strings.ToLower(mediaType)
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	if _, ok := reg.encoders[mediaType]; !ok {
+		reg.mediaTypes = append(reg.mediaTypes, mediaType)
+	}
+	reg.encoders[mediaType] = encoder
+}
+
+// Negotiate returns the media type and encoder that best satisfy the Accept header. An empty header accepts the
+// default media type. It returns false if none of the registered media types are acceptable.
+//
+// The media type with the highest quality wins. Ties go to the media type matched by the more specific media range,
+// then to the media type registered first.
+func (reg *EncoderRegistry) Negotiate(accept string) (string, Encoder, bool) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	if len(reg.mediaTypes) == 0 {
+		return "", nil, false
+	}
+
+	if strings.TrimSpace(accept) == "" {
+		mediaType := reg.mediaTypes[0]
+		return mediaType, reg.encoders[mediaType], true
+	}
+
+	ranges := parseAccept(accept)
+
+	var (
+		best        string
+		bestQuality float64
+		bestRank    = -1
+	)
+	for _, mediaType := range reg.mediaTypes {
+		quality, rank := matchMediaRange(ranges, mediaType)
+		if quality <= 0 {
+			continue
+		}
+
+		if quality > bestQuality || (quality == bestQuality && rank > bestRank) {
+			best, bestQuality, bestRank = mediaType, quality, rank
+		}
+	}
+
+	if best == "" {
+		return "", nil, false
+	}
+	return best, reg.encoders[best], true
+}
+
+// MediaTypes returns the registered media types, the default first.
+func (reg *EncoderRegistry) MediaTypes() []string {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	return slices.Clone(reg.mediaTypes)
+}
+
+// Encode writes v with the encoder that best satisfies the Accept header of the request. If none of the registered
+// media types are acceptable a 406 is written and the HTTPError returned.
+func (reg *EncoderRegistry) Encode(w http.ResponseWriter, r *http.Request, status int, v any) error {
+	_, encoder, ok := reg.Negotiate(r.Header.Get(HeaderAccept))
+	if !ok {
+		NotAcceptableHandler().ServeHTTP(w, r)
+		return NewHTTPError(http.StatusNotAcceptable, errNotAcceptable, "available: "+strings.Join(reg.MediaTypes(), ", "))
+	}
+
+	return encoder.Encode(w, r, status, v)
+}
+
+// defaultEncoders is the registry used by EncodeNegotiated.
+var defaultEncoders = NewEncoderRegistry()
+
+// RegisterEncoder sets the encoder used by EncodeNegotiated for the media type.
+func RegisterEncoder(mediaType string, encoder Encoder) {
+	defaultEncoders.Register(mediaType, encoder)
+}
+
+// EncodeNegotiated writes v in the media type that best satisfies the Accept header of the request, choosing from the
+// encoders added with RegisterEncoder. JSON is used when the request does not express a preference. If none of the
+// media types are acceptable a 406 is written and the HTTPError returned.
+func EncodeNegotiated[T any](w http.ResponseWriter, r *http.Request, status int, v T) error {
+	return defaultEncoders.Encode(w, r, status, v)
+}
+
+// mediaRange is a media range from an Accept header.
+type mediaRange struct {
+	typ     string
+	subtype string
+	quality float64
+}
+
+// specificity ranks how specific the media range is: "*/*" is 0, "type/*" is 1 and "type/subtype" is 2.
+func (m mediaRange) specificity() int {
+	switch {
+	case m.typ == mediaRangeWildcard:
+		return 0
+	case m.subtype == mediaRangeWildcard:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// matches returns true if the media range includes the media type.
+func (m mediaRange) matches(typ, subtype string) bool {
+	return (m.typ == mediaRangeWildcard || m.typ == typ) && (m.subtype == mediaRangeWildcard || m.subtype == subtype)
+}
+
+// parseAccept parses the media ranges of an Accept header, skipping any that are malformed. Media ranges without a
+// quality have a quality of one.
+func parseAccept(accept string) []mediaRange {
+	ranges := make([]mediaRange, 0)
+	for part := range strings.SplitSeq(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		typ, subtype, ok := strings.Cut(mediaType, "/")
+		if !ok {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			quality, err = strconv.ParseFloat(q, 64)
+			if err != nil || quality < 0 || quality > 1 {
+				continue
+			}
+		}
+
+		ranges = append(ranges, mediaRange{
+			typ:     typ,
+			subtype: subtype,
+			quality: quality,
+		})
+	}
+	return ranges
+}
+
+// matchMediaRange returns the quality the media ranges give the media type, taken from the most specific media range
+// that matches it, and the specificity of that range. It returns a quality of zero if no media range matches.
+func matchMediaRange(ranges []mediaRange, mediaType string) (quality float64, specificity int) {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+
+	specificity = -1
+	for _, m := range ranges {
+		if !m.matches(typ, subtype) {
+			continue
+		}
+
+		if s := m.specificity(); s > specificity {
+			quality, specificity = m.quality, s
+		}
+	}
+	return quality, specificity
+}
@@ -0,0 +1,168 @@
+package uhttp
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// textEncoder writes values with fmt in the given media type.
+func textEncoder(contentType string) Encoder {
+	return EncoderFunc(func(w http.ResponseWriter, _ *http.Request, status int, v any) error {
+		w.Header().Set(HeaderContentType, contentType)
+		w.WriteHeader(status)
+		_, err := fmt.Fprint(w, v)
+		return err
+	})
+}
+
+func TestEncoderRegistry_Negotiate(t *testing.T) {
+	reg := NewEncoderRegistry()
+	reg.Register("application/xml", textEncoder("application/xml"))
+	reg.Register("text/plain", textEncoder("text/plain"))
+
+	tests := []struct {
+		name   string
+		accept string
+		want   string
+		wantOk bool
+	}{
+		{
+			name:   "no preference",
+			accept: "",
+			want:   ContentTypeJSON,
+			wantOk: true,
+		},
+		{
+			name:   "any",
+			accept: "*/*",
+			want:   ContentTypeJSON,
+			wantOk: true,
+		},
+		{
+			name:   "exact",
+			accept: "application/xml",
+			want:   "application/xml",
+			wantOk: true,
+		},
+		{
+			name:   "case insensitive",
+			accept: "Text/Plain",
+			want:   "text/plain",
+			wantOk: true,
+		},
+		{
+			name:   "highest quality",
+			accept: "application/json;q=0.5, application/xml;q=0.8, text/plain;q=0.1",
+			want:   "application/xml",
+			wantOk: true,
+		},
+		{
+			name:   "more specific range wins a tie",
+			accept: "*/*, text/plain",
+			want:   "text/plain",
+			wantOk: true,
+		},
+		{
+			name:   "subtype wildcard",
+			accept: "text/*",
+			want:   "text/plain",
+			wantOk: true,
+		},
+		{
+			name:   "more specific range overrides the quality",
+			accept: "application/*, application/json;q=0",
+			want:   "application/xml",
+			wantOk: true,
+		},
+		{
+			name:   "malformed ranges are skipped",
+			accept: "application, text/plain;q=2, application/xml",
+			want:   "application/xml",
+			wantOk: true,
+		},
+		{
+			name:   "not acceptable",
+			accept: "image/png",
+			wantOk: false,
+		},
+		{
+			name:   "explicitly refused",
+			accept: "*/*;q=0",
+			wantOk: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, encoder, ok := reg.Negotiate(tt.accept)
+			require.Equal(t, tt.wantOk, ok)
+			require.Equal(t, tt.want, got)
+			require.Equal(t, tt.wantOk, encoder != nil)
+		})
+	}
+}
+
+func TestEncoderRegistry_Encode(t *testing.T) {
+	reg := NewEncoderRegistry()
+	reg.Register("text/plain", textEncoder("text/plain"))
+
+	tests := []struct {
+		name            string
+		accept          string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "default",
+			wantStatus:      http.StatusCreated,
+			wantContentType: ContentTypeJSON,
+			wantBody:        `"hello"` + "\n",
+		},
+		{
+			name:            "negotiated",
+			accept:          "text/plain",
+			wantStatus:      http.StatusCreated,
+			wantContentType: "text/plain",
+			wantBody:        "hello",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			r.Header.Set(HeaderAccept, tt.accept)
+
+			w := httptest.NewRecorder()
+			require.NoError(t, reg.Encode(w, r, http.StatusCreated, "hello"))
+			require.Equal(t, tt.wantStatus, w.Code)
+			require.Equal(t, tt.wantContentType, w.Header().Get(HeaderContentType))
+			require.Equal(t, tt.wantBody, w.Body.String())
+		})
+	}
+}
+
+func TestEncodeNegotiated_NotAcceptable(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	r.Header.Set(HeaderAccept, "image/png")
+	r.Header.Set(requestIDHeader, "123")
+	r = r.WithContext(RequestIDToContext(r.Context(), r))
+
+	w := httptest.NewRecorder()
+	err := EncodeNegotiated(w, r, http.StatusOK, "hello")
+
+	httpErr := new(HTTPError)
+	require.ErrorAs(t, err, &httpErr)
+	require.Equal(t, http.StatusNotAcceptable, httpErr.StatusCode())
+
+	require.Equal(t, http.StatusNotAcceptable, w.Code)
+	resp := new(HTTPError)
+	require.NoError(t, DecodeJSON(w.Result().Body, resp))
+	require.Equal(t, "not acceptable", resp.Detail)
+	require.Equal(t, []any{"method: GET", "path: /", "accept: image/png"}, resp.Details)
+	require.Equal(t, "123", resp.RequestId)
+}
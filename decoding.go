@@ -0,0 +1,264 @@
+package uhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var (
+	errEmptyBody            = errors.New("request body is empty")
+	errTrailingData         = errors.New("request body must only contain a single JSON value")
+	errUnsupportedMediaType = errors.New("unsupported media type")
+	errBodyTooLarge         = errors.New("request body too large")
+)
+
+// DecodeOption configures how a request body is decoded.
+type DecodeOption func(*decoder)
+
+type decoder struct {
+	// maxBytes caps the size of the body. Zero means unbounded.
+	maxBytes int64
+
+	// disallowUnknownFields rejects objects with fields that are not in the destination.
+	disallowUnknownFields bool
+
+	// rejectTrailingData rejects bodies with anything but whitespace after the JSON value.
+	rejectTrailingData bool
+
+	// contentTypes are the media types the Content-Type header must match. Empty skips the check.
+	contentTypes []string
+}
+
+// WithMaxBodyBytes rejects bodies larger than n bytes with a 413.
+func WithMaxBodyBytes(n int64) DecodeOption {
+	return func(d *decoder) {
+		d.maxBytes = n
+	}
+}
+
+// WithDisallowUnknownFields rejects objects with fields that do not match a field of the destination.
+func WithDisallowUnknownFields() DecodeOption {
+	return func(d *decoder) {
+		d.disallowUnknownFields = true
+	}
+}
+
+// WithRejectTrailingData rejects bodies that have anything but whitespace after the JSON value.
+func WithRejectTrailingData() DecodeOption {
+	return func(d *decoder) {
+		d.rejectTrailingData = true
+	}
+}
+
+// WithRequiredContentType rejects requests whose Content-Type is not one of the media types with a 415. Parameters
+// such as charset are ignored. When no media types are given, JSON is required.
+func WithRequiredContentType(mediaTypes ...string) DecodeOption {
+	return func(d *decoder) {
+		if len(mediaTypes) == 0 {
+			mediaTypes = []string{ContentTypeJSON}
+		}
+		d.contentTypes = mediaTypes
+	}
+}
+
+// WithStrictDecoding applies every check: the body is capped at maxBytes, must be JSON, and must hold exactly one
+// value with no unknown fields.
+func WithStrictDecoding(maxBytes int64) DecodeOption {
+	return func(d *decoder) {
+		WithMaxBodyBytes(maxBytes)(d)
+		WithDisallowUnknownFields()(d)
+		WithRejectTrailingData()(d)
+		WithRequiredContentType()(d)
+	}
+}
+
+func newDecoder(opts ...DecodeOption) *decoder {
+	d := new(decoder)
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// checkContentType returns an error if the Content-Type header is not one of the required media types.
+func (d *decoder) checkContentType(header string) error {
+	if len(d.contentTypes) == 0 {
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err == nil {
+		for _, want := range d.contentTypes {
+			if strings.EqualFold(mediaType, want) {
+				return nil
+			}
+		}
+	}
+
+	return &DecodeError{
+		Status: http.StatusUnsupportedMediaType,
+		Err:    fmt.Errorf("%w: %q", errUnsupportedMediaType, header),
+	}
+}
+
+// decodeJSON decodes a single JSON value from the reader into v.
+func (d *decoder) decodeJSON(reader io.Reader, v any) error {
+	if d.maxBytes > 0 {
+		reader = http.MaxBytesReader(nil, io.NopCloser(reader), d.maxBytes)
+	}
+
+	dec := json.NewDecoder(reader)
+	if d.disallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+
+	if err := dec.Decode(v); err != nil {
+		return newDecodeError(err)
+	}
+
+	if d.rejectTrailingData {
+		if err := dec.Decode(new(json.RawMessage)); !errors.Is(err, io.EOF) {
+			if err == nil {
+				err = errTrailingData
+			}
+			return newDecodeError(err)
+		}
+	}
+
+	return nil
+}
+
+// DecodeError is returned when a request body cannot be decoded. Status is the HTTP status that describes the failure:
+// 400 for malformed bodies, 413 for bodies that are too large and 415 for unsupported content types.
+type DecodeError struct {
+	// Status is the HTTP status code for the error.
+	Status int
+
+	// Field is the path of the field that could not be decoded, if the error relates to a single field.
+	Field string
+
+	// Offset is the byte offset of a syntax error in the body, or zero.
+	Offset int64
+
+	// Err is the underlying error.
+	Err error
+}
+
+// newDecodeError classifies an error returned while decoding a body.
+func newDecodeError(err error) *DecodeError {
+	var (
+		syntaxErr   *json.SyntaxError
+		typeErr     *json.UnmarshalTypeError
+		maxBytesErr *http.MaxBytesError
+	)
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return &DecodeError{
+			Status: http.StatusRequestEntityTooLarge,
+			Err:    fmt.Errorf("%w: limit is %d bytes", errBodyTooLarge, maxBytesErr.Limit),
+		}
+	case errors.As(err, &syntaxErr):
+		return &DecodeError{
+			Status: http.StatusBadRequest,
+			Offset: syntaxErr.Offset,
+			Err:    err,
+		}
+	case errors.As(err, &typeErr):
+		return &DecodeError{
+			Status: http.StatusBadRequest,
+			Field:  typeErr.Field,
+			Offset: typeErr.Offset,
+			Err:    err,
+		}
+	case errors.Is(err, io.EOF):
+		return &DecodeError{
+			Status: http.StatusBadRequest,
+			Err:    errEmptyBody,
+		}
+	}
+
+	// encoding/json does not export an error type for unknown fields.
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return &DecodeError{
+			Status: http.StatusBadRequest,
+			Field:  strings.Trim(field, `"`),
+			Err:    err,
+		}
+	}
+
+	return &DecodeError{
+		Status: http.StatusBadRequest,
+		Err:    err,
+	}
+}
+
+func (e *DecodeError) Error() string {
+	return "decode body: " + e.Err.Error()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// StatusCode returns the HTTP status code for the error.
+func (e *DecodeError) StatusCode() int {
+	return e.Status
+}
+
+// HTTPError converts the error to an HTTPError, with the field and offset in the details.
+func (e *DecodeError) HTTPError() *HTTPError {
+	details := make([]any, 0)
+	if e.Field != "" {
+		details = append(details, "field: "+e.Field)
+	}
+	if e.Offset > 0 {
+		details = append(details, "offset: "+strconv.FormatInt(e.Offset, 10))
+	}
+
+	return NewHTTPError(e.Status, e.Err, details...)
+}
+
+// httpErrorer is implemented by errors that describe themselves as an HTTPError.
+type httpErrorer interface {
+	HTTPError() *HTTPError
+}
+
+// DecodeErrorHandler writes the error returned from decoding a request. Errors that describe their own HTTPError, such
+// as DecodeError, are written with their status and details; any other error is written as a 400.
+func DecodeErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	var (
+		msg     *HTTPError
+		errorer httpErrorer
+	)
+	if errors.As(err, &errorer) {
+		msg = errorer.HTTPError()
+	} else {
+		msg = NewHTTPError(http.StatusBadRequest, err)
+	}
+
+	rw, ok := w.(*ResponseWriter)
+	if !ok {
+		rw = NewResponseWriter(w,
+			WithDefaultStatusCode(msg.Status),
+			WithDefaultHeader(HeaderRequestID, RequestIDFromContext(GenerateOrCopyRequestID(r.Context(), r))),
+			WithDefaultHeader(HeaderContentType, ContentTypeJSON),
+		)
+	}
+
+	// Is there a request ID in the context?
+	reqId := RequestIDFromContext(r.Context())
+	if reqId == "" {
+		reqId = RequestIDFromContext(GenerateRequestIDToContext(r))
+	}
+
+	msg.RequestId = reqId
+	rw.Header().Set(HeaderRequestID, reqId)
+	encodeError(rw, r, msg)
+}
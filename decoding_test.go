@@ -0,0 +1,141 @@
+package uhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeRequestJSON_Options(t *testing.T) {
+	type request struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		opts        []DecodeOption
+		want        request
+		wantStatus  int
+		wantField   string
+	}{
+		{
+			name:        "lenient by default",
+			body:        `{"name":"a","extra":true} trailing`,
+			contentType: "text/plain",
+			want:        request{Name: "a"},
+		},
+		{
+			name:        "strict",
+			body:        `{"name":"a","count":1}` + "\n",
+			contentType: "application/json; charset=utf-8",
+			opts:        []DecodeOption{WithStrictDecoding(1024)},
+			want:        request{Name: "a", Count: 1},
+		},
+		{
+			name:       "too large",
+			body:       `{"name":"` + strings.Repeat("a", 100) + `"}`,
+			opts:       []DecodeOption{WithMaxBodyBytes(64)},
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "unknown field",
+			body:       `{"name":"a","extra":true}`,
+			opts:       []DecodeOption{WithDisallowUnknownFields()},
+			wantStatus: http.StatusBadRequest,
+			wantField:  "extra",
+		},
+		{
+			name:       "trailing data",
+			body:       `{"name":"a"} {"name":"b"}`,
+			opts:       []DecodeOption{WithRejectTrailingData()},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "trailing garbage",
+			body:       `{"name":"a"} garbage`,
+			opts:       []DecodeOption{WithRejectTrailingData()},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "missing content type",
+			body:        `{"name":"a"}`,
+			opts:        []DecodeOption{WithRequiredContentType()},
+			wantStatus:  http.StatusUnsupportedMediaType,
+			contentType: "",
+		},
+		{
+			name:        "wrong content type",
+			body:        `{"name":"a"}`,
+			contentType: "text/plain",
+			opts:        []DecodeOption{WithRequiredContentType()},
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:       "wrong type",
+			body:       `{"count":"one"}`,
+			wantStatus: http.StatusBadRequest,
+			wantField:  "count",
+		},
+		{
+			name:       "syntax error",
+			body:       `{"name":}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "empty body",
+			body:       ``,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				r.Header.Set(HeaderContentType, tt.contentType)
+			}
+
+			got := request{}
+			err := DecodeRequestJSON(r, &got, tt.opts...)
+			if tt.wantStatus == 0 {
+				require.NoError(t, err)
+				require.Equal(t, tt.want, got)
+				return
+			}
+
+			decodeErr := new(DecodeError)
+			require.ErrorAs(t, err, &decodeErr)
+			require.Equal(t, tt.wantStatus, decodeErr.StatusCode())
+			require.Equal(t, tt.wantField, decodeErr.Field)
+		})
+	}
+}
+
+func TestDecodeErrorHandler(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"count":"one"}`))
+	r.Header.Set(requestIDHeader, "123")
+	r = r.WithContext(RequestIDToContext(r.Context(), r))
+
+	var body struct {
+		Count int `json:"count"`
+	}
+	err := DecodeRequestJSON(r, &body)
+	require.Error(t, err)
+
+	w := httptest.NewRecorder()
+	DecodeErrorHandler(w, r, err)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Equal(t, ContentTypeJSON, w.Header().Get(HeaderContentType))
+
+	resp := new(HTTPError)
+	require.NoError(t, DecodeJSON(w.Result().Body, resp))
+	require.Equal(t, http.StatusBadRequest, resp.Status)
+	require.Equal(t, []any{"field: count", "offset: 14"}, resp.Details)
+	require.Equal(t, "123", resp.RequestId)
+}
@@ -2,10 +2,10 @@ package uhttp
 
 import (
 	"encoding/json"
-	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
 )
 
 // MustEncode encodes a response as JSON and logs an error if it fails
@@ -23,18 +23,138 @@ func Encode[T any](w http.ResponseWriter, status int, v T) error {
 
 // EncodeJSON encodes a response as JSON
 func EncodeJSON[T any](w http.ResponseWriter, status int, v T) error {
-	w.Header().Set(ContentTypeJSON, ContentTypeJSON)
+	return defaultJSONEncoder.Encode(w, nil, status, v)
+}
+
+// defaultJSONEncoder is the encoder used by EncodeJSON.
+var defaultJSONEncoder = NewJSONEncoder()
+
+// JSONEncoderOption configures a JSON encoder.
+type JSONEncoderOption func(*jsonEncoder)
+
+type jsonEncoder struct {
+	// escapeHTML escapes <, > and & in strings.
+	escapeHTML bool
+
+	// prefix and indent are used to indent the output. The output is compact when both are empty.
+	prefix string
+	indent string
+
+	// prettyParam is the query parameter that asks for indented output. Empty disables it.
+	prettyParam string
+
+	// contentType is the Content-Type header sent with the response.
+	contentType string
+
+	// noSniff sends X-Content-Type-Options: nosniff.
+	noSniff bool
+}
+
+// WithJSONEscapeHTML sets whether <, > and & are escaped in strings. The default is true, matching encoding/json.
+func WithJSONEscapeHTML(escape bool) JSONEncoderOption {
+	return func(e *jsonEncoder) {
+		e.escapeHTML = escape
+	}
+}
+
+// WithJSONIndent indents every response, with each element on a new line beginning with prefix followed by copies of
+// indent.
+func WithJSONIndent(prefix, indent string) JSONEncoderOption {
+	return func(e *jsonEncoder) {
+		e.prefix = prefix
+		e.indent = indent
+	}
+}
+
+// WithJSONPrettyQuery indents the response with two spaces when the request has the query parameter, for example
+// "?pretty" or "?pretty=true".
+func WithJSONPrettyQuery(param string) JSONEncoderOption {
+	return func(e *jsonEncoder) {
+		e.prettyParam = param
+	}
+}
+
+// WithJSONCharset sends the Content-Type as "application/json; charset=utf-8".
+func WithJSONCharset() JSONEncoderOption {
+	return func(e *jsonEncoder) {
+		e.contentType = ContentTypeJSONUTF8
+	}
+}
+
+// WithJSONNoSniff sends "X-Content-Type-Options: nosniff", so that browsers do not guess the content type.
+func WithJSONNoSniff() JSONEncoderOption {
+	return func(e *jsonEncoder) {
+		e.noSniff = true
+	}
+}
+
+// NewJSONEncoder creates a new Encoder that writes JSON. Register it for ContentTypeJSON with RegisterEncoder to change
+// how EncodeNegotiated writes JSON.
+func NewJSONEncoder(opts ...JSONEncoderOption) Encoder {
+	e := &jsonEncoder{
+		escapeHTML:  true,
+		contentType: ContentTypeJSON,
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+}
+
+// Encode writes v as JSON with the status code.
+func (e *jsonEncoder) Encode(w http.ResponseWriter, r *http.Request, status int, v any) error {
+	w.Header().Set(HeaderContentType, e.contentType)
+	if e.noSniff {
+		w.Header().Set(HeaderContentTypeOptions, contentTypeOptionsNoSniff)
+	}
 	w.WriteHeader(status)
-	return json.NewEncoder(w).Encode(v)
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(e.escapeHTML)
+
+	switch {
+	case e.pretty(r):
+		enc.SetIndent("", "  ")
+	case e.prefix != "" || e.indent != "":
+		enc.SetIndent(e.prefix, e.indent)
+	}
+
+	return enc.Encode(v)
 }
 
-func DecodeRequestJSON[T any](r *http.Request, v *T) error {
-	return DecodeJSON[T](r.Body, v)
+// pretty returns true if the request asks for indented output.
+func (e *jsonEncoder) pretty(r *http.Request) bool {
+	if e.prettyParam == "" || r == nil {
+		return false
+	}
+
+	query := r.URL.Query()
+	if !query.Has(e.prettyParam) {
+		return false
+	}
+
+	value := query.Get(e.prettyParam)
+	if value == "" {
+		return true
+	}
+
+	pretty, err := strconv.ParseBool(value)
+	return err == nil && pretty
 }
 
-func DecodeJSON[T any](reader io.ReadCloser, v *T) error {
-	if err := json.NewDecoder(reader).Decode(&v); err != nil {
-		return fmt.Errorf("decode json: %w", err)
+// DecodeRequestJSON decodes the JSON body of the request into v. By default any body is accepted; the options add
+// limits and strict checks, and the error returned is a DecodeError that DecodeErrorHandler can write.
+func DecodeRequestJSON[T any](r *http.Request, v *T, opts ...DecodeOption) error {
+	d := newDecoder(opts...)
+	if err := d.checkContentType(r.Header.Get(HeaderContentType)); err != nil {
+		return err
 	}
-	return nil
+	return d.decodeJSON(r.Body, v)
+}
+
+// DecodeJSON decodes JSON from the reader into v. The Content-Type option does not apply, as there is no request.
+func DecodeJSON[T any](reader io.ReadCloser, v *T, opts ...DecodeOption) error {
+	return newDecoder(opts...).decodeJSON(reader, v)
 }
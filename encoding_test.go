@@ -292,3 +292,123 @@ func TestDecodeJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestEncodeJSON_Headers(t *testing.T) {
+	w := httptest.NewRecorder()
+	require.NoError(t, EncodeJSON(w, http.StatusOK, "hello"))
+
+	require.Equal(t, http.Header{
+		HeaderContentType: []string{ContentTypeJSON},
+	}, w.Result().Header)
+}
+
+func TestJSONEncoder(t *testing.T) {
+	type response struct {
+		Message string `json:"message"`
+	}
+
+	tests := []struct {
+		name        string
+		opts        []JSONEncoderOption
+		target      string
+		wantHeaders http.Header
+		wantBody    string
+	}{
+		{
+			name:   "defaults",
+			target: "/",
+			wantHeaders: http.Header{
+				HeaderContentType: []string{ContentTypeJSON},
+			},
+			wantBody: `{"message":"\u003cb\u003ehello\u003c/b\u003e"}` + "\n",
+		},
+		{
+			name:   "html escaping off",
+			opts:   []JSONEncoderOption{WithJSONEscapeHTML(false)},
+			target: "/",
+			wantHeaders: http.Header{
+				HeaderContentType: []string{ContentTypeJSON},
+			},
+			wantBody: `{"message":"<b>hello</b>"}` + "\n",
+		},
+		{
+			name:   "indent",
+			opts:   []JSONEncoderOption{WithJSONEscapeHTML(false), WithJSONIndent("", "\t")},
+			target: "/",
+			wantHeaders: http.Header{
+				HeaderContentType: []string{ContentTypeJSON},
+			},
+			wantBody: "{\n\t\"message\": \"<b>hello</b>\"\n}\n",
+		},
+		{
+			name:   "pretty query",
+			opts:   []JSONEncoderOption{WithJSONEscapeHTML(false), WithJSONPrettyQuery("pretty")},
+			target: "/?pretty",
+			wantHeaders: http.Header{
+				HeaderContentType: []string{ContentTypeJSON},
+			},
+			wantBody: "{\n  \"message\": \"<b>hello</b>\"\n}\n",
+		},
+		{
+			name:   "pretty query disabled",
+			opts:   []JSONEncoderOption{WithJSONEscapeHTML(false), WithJSONPrettyQuery("pretty")},
+			target: "/?pretty=false",
+			wantHeaders: http.Header{
+				HeaderContentType: []string{ContentTypeJSON},
+			},
+			wantBody: `{"message":"<b>hello</b>"}` + "\n",
+		},
+		{
+			name:   "charset and nosniff",
+			opts:   []JSONEncoderOption{WithJSONCharset(), WithJSONNoSniff()},
+			target: "/",
+			wantHeaders: http.Header{
+				HeaderContentType:        []string{"application/json; charset=utf-8"},
+				HeaderContentTypeOptions: []string{"nosniff"},
+			},
+			wantBody: `{"message":"\u003cb\u003ehello\u003c/b\u003e"}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, http.NoBody)
+			w := httptest.NewRecorder()
+
+			err := NewJSONEncoder(tt.opts...).Encode(w, r, http.StatusCreated, response{Message: "<b>hello</b>"})
+			require.NoError(t, err)
+
+			res := w.Result()
+			t.Cleanup(func() {
+				require.NoError(t, res.Body.Close())
+			})
+
+			require.Equal(t, http.StatusCreated, res.StatusCode)
+			require.Equal(t, tt.wantHeaders, res.Header)
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantBody, string(body))
+		})
+	}
+}
+
+func TestJSONEncoder_Server(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, NewJSONEncoder(WithJSONCharset(), WithJSONNoSniff()).Encode(w, r, http.StatusOK, "hello"))
+	}))
+	t.Cleanup(srv.Close)
+
+	res, err := srv.Client().Get(srv.URL)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, res.Body.Close())
+	})
+
+	// The server adds the Date and Content-Length headers on the wire.
+	res.Header.Del("Date")
+	require.Equal(t, http.Header{
+		"Content-Length":         []string{"8"},
+		HeaderContentType:        []string{"application/json; charset=utf-8"},
+		HeaderContentTypeOptions: []string{"nosniff"},
+	}, res.Header)
+}
@@ -0,0 +1,433 @@
+package uhttp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+)
+
+const (
+	// defaultMaxFormValueBytes caps the total size of the non-file values of a form, matching the limit net/http
+	// applies when parsing forms.
+	defaultMaxFormValueBytes = 10 << 20
+
+	// sniffLen is the number of bytes read from the start of a file to detect its content type.
+	sniffLen = 512
+)
+
+var (
+	errFileTooLarge       = errors.New("file too large")
+	errFormValuesTooLarge = errors.New("form values too large")
+	errTooManyFiles       = errors.New("only one file is allowed")
+
+	formFileType      = reflect.TypeFor[*FormFile]()
+	formFileSliceType = reflect.TypeFor[[]*FormFile]()
+)
+
+// FormFile is a file uploaded in a multipart form. Its content is streamed to the FileSink of the decoder rather than
+// held in memory.
+type FormFile struct {
+	// Field is the name of the form field the file was uploaded in.
+	Field string
+
+	// Filename is the name of the file given by the client.
+	Filename string
+
+	// Header is the MIME header of the part.
+	Header textproto.MIMEHeader
+
+	// ContentType is the content type detected from the content of the file. The Content-Type the client sent is in
+	// the Header.
+	ContentType string
+
+	// Size is the size of the file in bytes.
+	Size int64
+
+	// Path is where the file was written by the temp dir sink. Callers are responsible for removing it.
+	Path string
+}
+
+// FileSink stores the content of uploaded files.
+type FileSink interface {
+	// Store consumes the content of the file. It may record where the file was stored on the FormFile. Errors returned
+	// while reading the content must be returned, so that size limits are reported.
+	Store(ctx context.Context, file *FormFile, content io.Reader) error
+}
+
+// FileSinkFunc is an adapter to allow the use of ordinary functions as a FileSink.
+type FileSinkFunc func(ctx context.Context, file *FormFile, content io.Reader) error
+
+// Store calls f(ctx, file, content).
+func (f FileSinkFunc) Store(ctx context.Context, file *FormFile, content io.Reader) error {
+	return f(ctx, file, content)
+}
+
+// NewTempDirSink creates a FileSink that writes each file to a new file in dir and sets its Path. An empty dir uses the
+// default directory for temporary files.
+func NewTempDirSink(dir string) FileSink {
+	return FileSinkFunc(func(_ context.Context, file *FormFile, content io.Reader) error {
+		f, err := os.CreateTemp(dir, "upload-*")
+		if err != nil {
+			return fmt.Errorf("create temp file: %w", err)
+		}
+
+		if _, err := io.Copy(f, content); err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+			return fmt.Errorf("write temp file: %w", err)
+		}
+
+		if err := f.Close(); err != nil {
+			_ = os.Remove(f.Name())
+			return fmt.Errorf("close temp file: %w", err)
+		}
+
+		file.Path = f.Name()
+		return nil
+	})
+}
+
+// NewWriterSink creates a FileSink that writes the content of every file to w, one after another.
+func NewWriterSink(w io.Writer) FileSink {
+	return FileSinkFunc(func(_ context.Context, _ *FormFile, content io.Reader) error {
+		_, err := io.Copy(w, content)
+		return err
+	})
+}
+
+// FormOption configures how a form body is decoded.
+type FormOption func(*formDecoder)
+
+type formDecoder struct {
+	// maxBytes caps the size of the whole body. Zero means unbounded.
+	maxBytes int64
+
+	// maxFileBytes caps the size of each file. Zero means unbounded.
+	maxFileBytes int64
+
+	// maxValueBytes caps the total size of the non-file values. Zero means unbounded.
+	maxValueBytes int64
+
+	// fileTypes are the media ranges the detected content type of each file must match. Empty allows any type.
+	fileTypes []mediaRange
+
+	// sink stores the content of uploaded files.
+	sink FileSink
+}
+
+// WithMaxFormBytes rejects form bodies larger than n bytes, including any files, with a 413.
+func WithMaxFormBytes(n int64) FormOption {
+	return func(d *formDecoder) {
+		d.maxBytes = n
+	}
+}
+
+// WithMaxFileBytes rejects uploaded files larger than n bytes with a 413.
+func WithMaxFileBytes(n int64) FormOption {
+	return func(d *formDecoder) {
+		d.maxFileBytes = n
+	}
+}
+
+// WithMaxFormValueBytes rejects forms whose non-file values are larger than n bytes in total with a 413. The default
+// is 10MB, and zero leaves the values unbounded.
+func WithMaxFormValueBytes(n int64) FormOption {
+	return func(d *formDecoder) {
+		d.maxValueBytes = n
+	}
+}
+
+// WithAllowedFileTypes rejects uploaded files whose detected content type does not match one of the media ranges, such
+// as "image/png" or "image/*", with a 415.
+func WithAllowedFileTypes(mediaRanges ...string) FormOption {
+	return func(d *formDecoder) {
+		d.fileTypes = parseAccept(strings.Join(mediaRanges, ","))
+	}
+}
+
+// WithFileSink sets where uploaded files are stored. The default writes them to the default directory for temporary
+// files.
+func WithFileSink(sink FileSink) FormOption {
+	return func(d *formDecoder) {
+		d.sink = sink
+	}
+}
+
+func newFormDecoder(opts ...FormOption) *formDecoder {
+	d := &formDecoder{
+		maxValueBytes: defaultMaxFormValueBytes,
+		sink:          NewTempDirSink(""),
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// DecodeRequestForm decodes a URL encoded or multipart form body into v. Fields are named by `form` struct tags and
+// take the same types and `default` tags as Bind. Files are bound to fields of type *FormFile or []*FormFile, and are
+// streamed to the FileSink as they are read; files in fields that are not bound are skipped. A second file for a
+// *FormFile field is rejected with a 400.
+//
+// Bodies that are too large, or not a form, are returned as a DecodeError with a status of 413 or 415, and values that
+// cannot be converted as a BindError. Both can be written with DecodeErrorHandler. If decoding fails, any files already
+// written by the temp dir sink are removed.
+func DecodeRequestForm[T any](r *http.Request, v *T, opts ...FormOption) error {
+	rv := reflect.ValueOf(v).Elem()
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("decode form: %T is not a struct", *v)
+	}
+
+	d := newFormDecoder(opts...)
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get(HeaderContentType))
+	if err != nil || (mediaType != ContentTypeForm && mediaType != ContentTypeMultipartForm) {
+		return &DecodeError{
+			Status: http.StatusUnsupportedMediaType,
+			Err:    fmt.Errorf("%w: %q", errUnsupportedMediaType, r.Header.Get(HeaderContentType)),
+		}
+	}
+
+	if d.maxBytes > 0 {
+		r.Body = http.MaxBytesReader(nil, r.Body, d.maxBytes)
+	}
+
+	valueFields := make([]bindField, 0)
+	fileFields := make(map[string]bindField)
+	multiFileFields := make(map[string]bool)
+	for _, field := range bindFieldsOf(rv.Type(), []string{bindSourceForm}) {
+		switch fieldType := rv.FieldByIndex(field.index).Type(); fieldType {
+		case formFileType, formFileSliceType:
+			fileFields[field.name] = field
+			multiFileFields[field.name] = fieldType == formFileSliceType
+		default:
+			valueFields = append(valueFields, field)
+		}
+	}
+
+	var (
+		values url.Values
+		files  map[string][]*FormFile
+	)
+	if mediaType == ContentTypeForm {
+		values, err = d.readURLEncoded(r.Body)
+	} else {
+		values, files, err = d.readMultipart(r, multiFileFields)
+	}
+	if err != nil {
+		removeFormFiles(files)
+		return err
+	}
+
+	paramErrs := bindValues(rv, valueFields, func(field bindField) []string {
+		return values[field.name]
+	})
+	if len(paramErrs) > 0 {
+		removeFormFiles(files)
+		return &BindError{Params: paramErrs}
+	}
+
+	for name, field := range fileFields {
+		fieldFiles, ok := files[name]
+		if !ok {
+			continue
+		}
+
+		fv := rv.FieldByIndex(field.index)
+		if fv.Type() == formFileType {
+			fv.Set(reflect.ValueOf(fieldFiles[0]))
+			continue
+		}
+		fv.Set(reflect.ValueOf(fieldFiles))
+	}
+
+	return nil
+}
+
+// readURLEncoded reads the values of a URL encoded form body.
+func (d *formDecoder) readURLEncoded(body io.Reader) (url.Values, error) {
+	data, err := io.ReadAll(io.LimitReader(body, d.valueLimit()+1))
+	if err != nil {
+		return nil, newDecodeError(err)
+	}
+
+	if int64(len(data)) > d.valueLimit() {
+		return nil, &DecodeError{
+			Status: http.StatusRequestEntityTooLarge,
+			Err:    fmt.Errorf("%w: limit is %d bytes", errFormValuesTooLarge, d.maxValueBytes),
+		}
+	}
+
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return nil, newDecodeError(err)
+	}
+	return values, nil
+}
+
+// valueLimit returns the total size allowed for the non-file values.
+func (d *formDecoder) valueLimit() int64 {
+	if d.maxValueBytes <= 0 {
+		// One less than the largest size, so that reading one byte past the limit does not overflow.
+		return math.MaxInt64 - 1
+	}
+	return d.maxValueBytes
+}
+
+// readMultipart reads the values of a multipart form body and streams the files of the file fields to the sink. The
+// file fields map to whether they hold more than one file. The files stored so far are returned with any error, so
+// they can be removed.
+func (d *formDecoder) readMultipart(r *http.Request, fileFields map[string]bool) (url.Values, map[string][]*FormFile, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, nil, newDecodeError(err)
+	}
+
+	values := make(url.Values)
+	files := make(map[string][]*FormFile)
+	valueBytes := d.valueLimit()
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return values, files, nil
+		} else if err != nil {
+			return nil, files, newDecodeError(err)
+		}
+
+		name := part.FormName()
+		if part.FileName() == "" {
+			value, err := io.ReadAll(io.LimitReader(part, valueBytes+1))
+			if err != nil {
+				return nil, files, newDecodeError(err)
+			}
+
+			valueBytes -= int64(len(value))
+			if valueBytes < 0 {
+				return nil, files, &DecodeError{
+					Status: http.StatusRequestEntityTooLarge,
+					Field:  name,
+					Err:    fmt.Errorf("%w: limit is %d bytes", errFormValuesTooLarge, d.maxValueBytes),
+				}
+			}
+
+			values.Add(name, string(value))
+			continue
+		}
+
+		multiple, ok := fileFields[name]
+		if !ok {
+			continue
+		} else if !multiple && len(files[name]) > 0 {
+			return nil, files, &DecodeError{
+				Status: http.StatusBadRequest,
+				Field:  name,
+				Err:    errTooManyFiles,
+			}
+		}
+
+		file, err := d.storeFile(r.Context(), part)
+		if file != nil {
+			files[name] = append(files[name], file)
+		}
+		if err != nil {
+			return nil, files, err
+		}
+	}
+}
+
+// storeFile detects the content type of a file part, checks it is allowed and streams the file to the sink.
+func (d *formDecoder) storeFile(ctx context.Context, part *multipart.Part) (*FormFile, error) {
+	name := part.FormName()
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(part, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, newFormFileError(name, d.maxFileBytes, err)
+	}
+	head = head[:n]
+
+	contentType := http.DetectContentType(head)
+	if len(d.fileTypes) > 0 {
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		if quality, _ := matchMediaRange(d.fileTypes, mediaType); quality == 0 {
+			return nil, &DecodeError{
+				Status: http.StatusUnsupportedMediaType,
+				Field:  name,
+				Err:    fmt.Errorf("%w: %q", errUnsupportedMediaType, mediaType),
+			}
+		}
+	}
+
+	file := &FormFile{
+		Field:       name,
+		Filename:    part.FileName(),
+		Header:      part.Header,
+		ContentType: contentType,
+	}
+
+	content := &fileReader{
+		r:     io.MultiReader(bytes.NewReader(head), part),
+		limit: d.maxFileBytes,
+	}
+	err = d.sink.Store(ctx, file, content)
+	file.Size = content.n
+	if err != nil {
+		return file, newFormFileError(name, d.maxFileBytes, err)
+	}
+
+	return file, nil
+}
+
+// newFormFileError classifies an error returned while storing a file.
+func newFormFileError(field string, limit int64, err error) *DecodeError {
+	if errors.Is(err, errFileTooLarge) {
+		return &DecodeError{
+			Status: http.StatusRequestEntityTooLarge,
+			Field:  field,
+			Err:    fmt.Errorf("%w: limit is %d bytes", errFileTooLarge, limit),
+		}
+	}
+
+	decodeErr := newDecodeError(err)
+	decodeErr.Field = field
+	return decodeErr
+}
+
+// removeFormFiles removes the files written by the temp dir sink.
+func removeFormFiles(files map[string][]*FormFile) {
+	for _, fieldFiles := range files {
+		for _, file := range fieldFiles {
+			if file.Path != "" {
+				_ = os.Remove(file.Path)
+			}
+		}
+	}
+}
+
+// fileReader counts the bytes read from a file and fails once more than limit bytes have been read.
+type fileReader struct {
+	r     io.Reader
+	n     int64
+	limit int64
+}
+
+func (f *fileReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	f.n += int64(n)
+	if f.limit > 0 && f.n > f.limit {
+		return n, errFileTooLarge
+	}
+	return n, err
+}
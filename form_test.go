@@ -0,0 +1,272 @@
+package uhttp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type formRequest struct {
+	Name   string      `form:"name"`
+	Age    int         `form:"age" default:"18"`
+	Tags   []string    `form:"tag"`
+	Avatar *FormFile   `form:"avatar"`
+	Docs   []*FormFile `form:"doc"`
+}
+
+// formPart is a part of a multipart test body. Parts with a filename are files.
+type formPart struct {
+	name     string
+	filename string
+	content  string
+}
+
+func newMultipartRequest(t *testing.T, parts ...formPart) *http.Request {
+	t.Helper()
+
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	for _, part := range parts {
+		var (
+			w   io.Writer
+			err error
+		)
+		if part.filename != "" {
+			w, err = mw.CreateFormFile(part.name, part.filename)
+		} else {
+			w, err = mw.CreateFormField(part.name)
+		}
+		require.NoError(t, err)
+
+		_, err = io.WriteString(w, part.content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, mw.Close())
+
+	r := httptest.NewRequest(http.MethodPost, "/", body)
+	r.Header.Set(HeaderContentType, mw.FormDataContentType())
+	return r
+}
+
+func TestDecodeRequestForm_URLEncoded(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("name=alice&tag=a&tag=b"))
+	r.Header.Set(HeaderContentType, ContentTypeForm)
+
+	got := new(formRequest)
+	require.NoError(t, DecodeRequestForm(r, got))
+	require.Equal(t, &formRequest{Name: "alice", Age: 18, Tags: []string{"a", "b"}}, got)
+}
+
+func TestDecodeRequestForm_Multipart(t *testing.T) {
+	dir := t.TempDir()
+	r := newMultipartRequest(t,
+		formPart{name: "name", content: "bob"},
+		formPart{name: "age", content: "30"},
+		formPart{name: "avatar", filename: "a.png", content: "\x89PNG\r\n\x1a\nimage"},
+		formPart{name: "doc", filename: "a.txt", content: "first"},
+		formPart{name: "doc", filename: "b.txt", content: "second"},
+		formPart{name: "ignored", filename: "c.txt", content: "skipped"},
+	)
+
+	got := new(formRequest)
+	require.NoError(t, DecodeRequestForm(r, got, WithFileSink(NewTempDirSink(dir))))
+	require.Equal(t, "bob", got.Name)
+	require.Equal(t, 30, got.Age)
+
+	require.Equal(t, "avatar", got.Avatar.Field)
+	require.Equal(t, "a.png", got.Avatar.Filename)
+	require.Equal(t, "image/png", got.Avatar.ContentType)
+	require.Equal(t, int64(13), got.Avatar.Size)
+	content, err := os.ReadFile(got.Avatar.Path)
+	require.NoError(t, err)
+	require.Equal(t, "\x89PNG\r\n\x1a\nimage", string(content))
+
+	require.Len(t, got.Docs, 2)
+	require.Equal(t, "b.txt", got.Docs[1].Filename)
+	require.Equal(t, "text/plain; charset=utf-8", got.Docs[1].ContentType)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+}
+
+func TestDecodeRequestForm_Sinks(t *testing.T) {
+	t.Run("writer", func(t *testing.T) {
+		r := newMultipartRequest(t,
+			formPart{name: "doc", filename: "a.txt", content: "first"},
+			formPart{name: "doc", filename: "b.txt", content: "second"},
+		)
+
+		buf := new(bytes.Buffer)
+		got := new(formRequest)
+		require.NoError(t, DecodeRequestForm(r, got, WithFileSink(NewWriterSink(buf))))
+		require.Equal(t, "firstsecond", buf.String())
+		require.Empty(t, got.Docs[0].Path)
+	})
+
+	t.Run("func", func(t *testing.T) {
+		r := newMultipartRequest(t, formPart{name: "avatar", filename: "a.txt", content: "hello"})
+
+		stored := make(map[string]string)
+		sink := FileSinkFunc(func(_ context.Context, file *FormFile, content io.Reader) error {
+			data, err := io.ReadAll(content)
+			stored[file.Filename] = string(data)
+			return err
+		})
+
+		got := new(formRequest)
+		require.NoError(t, DecodeRequestForm(r, got, WithFileSink(sink)))
+		require.Equal(t, map[string]string{"a.txt": "hello"}, stored)
+		require.Equal(t, int64(5), got.Avatar.Size)
+	})
+}
+
+func TestDecodeRequestForm_UnboundedValues(t *testing.T) {
+	name := strings.Repeat("a", 100)
+
+	t.Run("multipart", func(t *testing.T) {
+		r := newMultipartRequest(t, formPart{name: "name", content: name})
+
+		got := new(formRequest)
+		require.NoError(t, DecodeRequestForm(r, got, WithMaxFormValueBytes(0)))
+		require.Equal(t, name, got.Name)
+	})
+
+	t.Run("url encoded", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("name="+name))
+		r.Header.Set(HeaderContentType, ContentTypeForm)
+
+		got := new(formRequest)
+		require.NoError(t, DecodeRequestForm(r, got, WithMaxFormValueBytes(0)))
+		require.Equal(t, name, got.Name)
+	})
+}
+
+func TestDecodeRequestForm_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		request    func(t *testing.T) *http.Request
+		opts       []FormOption
+		wantStatus int
+		wantField  string
+	}{
+		{
+			name: "unsupported content type",
+			request: func(t *testing.T) *http.Request {
+				r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+				r.Header.Set(HeaderContentType, ContentTypeJSON)
+				return r
+			},
+			wantStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name: "file too large",
+			request: func(t *testing.T) *http.Request {
+				return newMultipartRequest(t, formPart{name: "avatar", filename: "a.txt", content: strings.Repeat("a", 100)})
+			},
+			opts:       []FormOption{WithMaxFileBytes(10)},
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantField:  "avatar",
+		},
+		{
+			name: "body too large",
+			request: func(t *testing.T) *http.Request {
+				return newMultipartRequest(t, formPart{name: "avatar", filename: "a.txt", content: strings.Repeat("a", 1000)})
+			},
+			opts:       []FormOption{WithMaxFormBytes(100)},
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "values too large",
+			request: func(t *testing.T) *http.Request {
+				return newMultipartRequest(t, formPart{name: "name", content: strings.Repeat("a", 100)})
+			},
+			opts:       []FormOption{WithMaxFormValueBytes(10)},
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantField:  "name",
+		},
+		{
+			name: "url encoded values too large",
+			request: func(t *testing.T) *http.Request {
+				r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("name="+strings.Repeat("a", 100)))
+				r.Header.Set(HeaderContentType, ContentTypeForm)
+				return r
+			},
+			opts:       []FormOption{WithMaxFormValueBytes(10)},
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "file type not allowed",
+			request: func(t *testing.T) *http.Request {
+				return newMultipartRequest(t, formPart{name: "avatar", filename: "a.png", content: "not an image"})
+			},
+			opts:       []FormOption{WithAllowedFileTypes("image/*")},
+			wantStatus: http.StatusUnsupportedMediaType,
+			wantField:  "avatar",
+		},
+		{
+			name: "second file for a single file field",
+			request: func(t *testing.T) *http.Request {
+				return newMultipartRequest(t,
+					formPart{name: "avatar", filename: "a.txt", content: "first"},
+					formPart{name: "avatar", filename: "b.txt", content: "second"},
+				)
+			},
+			wantStatus: http.StatusBadRequest,
+			wantField:  "avatar",
+		},
+		{
+			name: "malformed multipart",
+			request: func(t *testing.T) *http.Request {
+				r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("--x\r\nbad header\r\n\r\nvalue\r\n--x--\r\n"))
+				r.Header.Set(HeaderContentType, ContentTypeMultipartForm+"; boundary=x")
+				return r
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			opts := append([]FormOption{WithFileSink(NewTempDirSink(dir))}, tt.opts...)
+
+			err := DecodeRequestForm(tt.request(t), new(formRequest), opts...)
+
+			decodeErr := new(DecodeError)
+			require.ErrorAs(t, err, &decodeErr)
+			require.Equal(t, tt.wantStatus, decodeErr.StatusCode())
+			require.Equal(t, tt.wantField, decodeErr.Field)
+
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			require.Empty(t, entries)
+		})
+	}
+}
+
+func TestDecodeRequestForm_BindError(t *testing.T) {
+	dir := t.TempDir()
+	r := newMultipartRequest(t,
+		formPart{name: "avatar", filename: "a.txt", content: "hello"},
+		formPart{name: "age", content: "old"},
+	)
+
+	err := DecodeRequestForm(r, new(formRequest), WithFileSink(NewTempDirSink(dir)))
+
+	bindErr := new(BindError)
+	require.ErrorAs(t, err, &bindErr)
+	require.Equal(t, []ParamError{{In: "form", Name: "age", Message: `invalid integer "old"`}}, bindErr.Params)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
@@ -9,6 +9,10 @@ var (
 	errNotFound         = errors.New("not found")
 	errMethodNotAllowed = errors.New("method not allowed")
 	errUnauthorized     = errors.New("unauthorized")
+	errTooManyRequests  = errors.New("too many requests")
+	errNotAcceptable    = errors.New("not acceptable")
+	errUnavailable      = errors.New("service unavailable")
+	errInternal         = errors.New("internal server error")
 )
 
 // WrapHandler wraps the handler with the specified middlewares, making the execution order the inverse of the parameter declaration.
@@ -54,7 +58,7 @@ func NotFoundHandler() http.HandlerFunc {
 
 		msg.RequestId = reqId
 		rw.Header().Set(HeaderRequestID, reqId)
-		MustEncode(rw, http.StatusNotFound, msg)
+		encodeError(rw, r, msg)
 	}
 }
 
@@ -89,7 +93,7 @@ func MethodNotAllowedHandler() http.HandlerFunc {
 
 		msg.RequestId = reqId
 		rw.Header().Set(HeaderRequestID, reqId)
-		MustEncode(rw, http.StatusMethodNotAllowed, msg)
+		encodeError(rw, r, msg)
 	}
 }
 
@@ -124,7 +128,153 @@ func UnauthorizedHandler() http.HandlerFunc {
 
 		msg.RequestId = reqId
 		rw.Header().Set(HeaderRequestID, reqId)
-		MustEncode(rw, http.StatusUnauthorized, msg)
+		encodeError(rw, r, msg)
+	}
+}
+
+// TooManyRequestsHandler returns a handler that returns a 429 response.
+func TooManyRequestsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rw, ok := w.(*ResponseWriter)
+		if !ok {
+			rw = NewResponseWriter(w,
+				WithDefaultStatusCode(http.StatusTooManyRequests),
+				WithDefaultHeader(HeaderRequestID, RequestIDFromContext(GenerateOrCopyRequestID(r.Context(), r))),
+				WithDefaultHeader(HeaderContentType, ContentTypeJSON),
+			)
+		}
+
+		details := []any{
+			"method: " + r.Method,
+			"path: " + r.URL.Path,
+		}
+
+		if r.URL.RawQuery != "" {
+			details = append(details, "query: "+r.URL.RawQuery)
+		}
+
+		msg := NewHTTPError(http.StatusTooManyRequests, errTooManyRequests, details...)
+
+		// Is there a request ID in the context?
+		reqId := RequestIDFromContext(r.Context())
+		if reqId == "" {
+			reqId = RequestIDFromContext(GenerateRequestIDToContext(r))
+		}
+
+		msg.RequestId = reqId
+		rw.Header().Set(HeaderRequestID, reqId)
+		encodeError(rw, r, msg)
+	}
+}
+
+// NotAcceptableHandler returns a handler that returns a 406 response, for requests whose Accept header cannot be
+// satisfied.
+func NotAcceptableHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rw, ok := w.(*ResponseWriter)
+		if !ok {
+			rw = NewResponseWriter(w,
+				WithDefaultStatusCode(http.StatusNotAcceptable),
+				WithDefaultHeader(HeaderRequestID, RequestIDFromContext(GenerateOrCopyRequestID(r.Context(), r))),
+				WithDefaultHeader(HeaderContentType, ContentTypeJSON),
+			)
+		}
+
+		details := []any{
+			"method: " + r.Method,
+			"path: " + r.URL.Path,
+		}
+
+		if r.URL.RawQuery != "" {
+			details = append(details, "query: "+r.URL.RawQuery)
+		}
+
+		if accept := r.Header.Get(HeaderAccept); accept != "" {
+			details = append(details, "accept: "+accept)
+		}
+
+		msg := NewHTTPError(http.StatusNotAcceptable, errNotAcceptable, details...)
+
+		// Is there a request ID in the context?
+		reqId := RequestIDFromContext(r.Context())
+		if reqId == "" {
+			reqId = RequestIDFromContext(GenerateRequestIDToContext(r))
+		}
+
+		msg.RequestId = reqId
+		rw.Header().Set(HeaderRequestID, reqId)
+		encodeError(rw, r, msg)
+	}
+}
+
+// ServiceUnavailableHandler returns a handler that returns a 503 response.
+func ServiceUnavailableHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rw, ok := w.(*ResponseWriter)
+		if !ok {
+			rw = NewResponseWriter(w,
+				WithDefaultStatusCode(http.StatusServiceUnavailable),
+				WithDefaultHeader(HeaderRequestID, RequestIDFromContext(GenerateOrCopyRequestID(r.Context(), r))),
+				WithDefaultHeader(HeaderContentType, ContentTypeJSON),
+			)
+		}
+
+		details := []any{
+			"method: " + r.Method,
+			"path: " + r.URL.Path,
+		}
+
+		if r.URL.RawQuery != "" {
+			details = append(details, "query: "+r.URL.RawQuery)
+		}
+
+		msg := NewHTTPError(http.StatusServiceUnavailable, errUnavailable, details...)
+
+		// Is there a request ID in the context?
+		reqId := RequestIDFromContext(r.Context())
+		if reqId == "" {
+			reqId = RequestIDFromContext(GenerateRequestIDToContext(r))
+		}
+
+		msg.RequestId = reqId
+		rw.Header().Set(HeaderRequestID, reqId)
+		encodeError(rw, r, msg)
+	}
+}
+
+// InternalServerErrorHandler returns a handler that returns a 500 response. The cause is not given to the client, so
+// it should be logged before the handler is called.
+func InternalServerErrorHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rw, ok := w.(*ResponseWriter)
+		if !ok {
+			rw = NewResponseWriter(w,
+				WithDefaultStatusCode(http.StatusInternalServerError),
+				WithDefaultHeader(HeaderRequestID, RequestIDFromContext(GenerateOrCopyRequestID(r.Context(), r))),
+				WithDefaultHeader(HeaderContentType, ContentTypeJSON),
+			)
+		}
+
+		details := []any{
+			"method: " + r.Method,
+			"path: " + r.URL.Path,
+		}
+
+		if r.URL.RawQuery != "" {
+			details = append(details, "query: "+r.URL.RawQuery)
+		}
+
+		msg := NewHTTPError(http.StatusInternalServerError, errInternal, details...)
+
+		// Is there a request ID in the context?
+		reqId := RequestIDFromContext(r.Context())
+		if reqId == "" {
+			reqId = RequestIDFromContext(GenerateRequestIDToContext(r))
+		}
+
+		msg.RequestId = reqId
+		rw.Header().Set(HeaderRequestID, reqId)
+		encodeError(rw, r, msg)
 	}
 }
 
@@ -148,5 +298,5 @@ func GenericErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
 
 	msg.RequestId = reqId
 	rw.Header().Set(HeaderRequestID, reqId)
-	MustEncode(rw, http.StatusBadRequest, msg)
+	encodeError(rw, r, msg)
 }
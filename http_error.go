@@ -1,9 +1,11 @@
 package uhttp
 
 import (
+	"encoding/xml"
 	"net/http"
 
 	"github.com/jacobbrewer1/uhttp/common"
+	"github.com/vmihailenco/msgpack/v5"
 )
 
 type StatusCoder interface { // nolint:iface // This is an interface used by external libraries
@@ -33,6 +35,34 @@ func (e *HTTPError) SetRequestId(requestId string) {
 	e.RequestId = requestId
 }
 
+// MarshalXML writes the error as an <error> element, with the same field names as its JSON.
+func (e *HTTPError) MarshalXML(enc *xml.Encoder, _ xml.StartElement) error {
+	return enc.Encode(struct {
+		XMLName   xml.Name `xml:"error"`
+		Title     string   `xml:"title"`
+		Status    int      `xml:"status"`
+		Detail    string   `xml:"detail"`
+		Details   []any    `xml:"details>item"`
+		RequestId string   `xml:"request_id"`
+	}{
+		Title:     e.Title,
+		Status:    e.Status,
+		Detail:    e.Detail,
+		Details:   e.Details,
+		RequestId: e.RequestId,
+	})
+}
+
+// EncodeMsgpack writes the error message as MessagePack, leaving out the wrapped error.
+func (e *HTTPError) EncodeMsgpack(enc *msgpack.Encoder) error {
+	return enc.Encode(&e.ErrorMessage)
+}
+
+// DecodeMsgpack reads the error message from MessagePack.
+func (e *HTTPError) DecodeMsgpack(dec *msgpack.Decoder) error {
+	return dec.Decode(&e.ErrorMessage)
+}
+
 // NewHTTPError creates a new HTTPError.
 func NewHTTPError(code int, err error, details ...any) *HTTPError {
 	errMsg := &common.ErrorMessage{
@@ -0,0 +1,223 @@
+package uhttp
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RequestPriority is the priority of a request when the server is shedding load.
+type RequestPriority int
+
+const (
+	// PriorityLow requests are shed first, once the server is using part of its concurrency limit.
+	PriorityLow RequestPriority = iota
+
+	// PriorityNormal requests are shed once the server reaches its concurrency limit.
+	PriorityNormal
+
+	// PriorityCritical requests are never shed.
+	PriorityCritical
+)
+
+// RequestPriorityFunc returns the priority of the request.
+type RequestPriorityFunc func(r *http.Request) RequestPriority
+
+// DefaultRequestPriority returns a classifier that treats requests to the health check paths and internal traffic, as
+// reported by IsInternal, as critical and everything else as normal.
+func DefaultRequestPriority(healthPaths ...string) RequestPriorityFunc {
+	return func(r *http.Request) RequestPriority {
+		for _, path := range healthPaths {
+			if r.URL.Path == path {
+				return PriorityCritical
+			}
+		}
+
+		if IsInternal(r) {
+			return PriorityCritical
+		}
+
+		return PriorityNormal
+	}
+}
+
+// LoadShedOption configures the load shedding middleware.
+type LoadShedOption func(*loadShedder)
+
+// loadShedder adapts a concurrency limit to the latency of the requests it serves, using additive increase and
+// multiplicative decrease (AIMD). Every request that completes within the target latency while the server is busy
+// raises the limit by one, and every request that exceeds it scales the limit down by the backoff ratio.
+type loadShedder struct {
+	mu sync.Mutex
+
+	// inFlight is the number of requests being served.
+	inFlight int
+
+	// limit is the current concurrency limit.
+	limit float64
+
+	// minLimit and maxLimit bound the concurrency limit.
+	minLimit float64
+	maxLimit float64
+
+	// targetLatency is the latency above which a request is taken as a sign of overload.
+	targetLatency time.Duration
+
+	// backoff is the ratio the limit is scaled by when a request exceeds the target latency.
+	backoff float64
+
+	// lowPriorityShare is the share of the limit available to low priority requests.
+	lowPriorityShare float64
+
+	// priorityFunc classifies the requests.
+	priorityFunc RequestPriorityFunc
+
+	// retryAfter is sent in the Retry-After header of rejected requests. Zero omits the header.
+	retryAfter time.Duration
+
+	// clock times the requests.
+	clock Clock
+}
+
+// WithLoadShedLimits sets the initial concurrency limit and the bounds it adapts within. The defaults are 20, 1 and
+// 1000.
+func WithLoadShedLimits(initial, minLimit, maxLimit int) LoadShedOption {
+	return func(s *loadShedder) {
+		s.limit = float64(initial)
+		s.minLimit = float64(minLimit)
+		s.maxLimit = float64(maxLimit)
+	}
+}
+
+// WithLoadShedTargetLatency sets the latency above which a request lowers the concurrency limit. The default is
+// 500ms.
+func WithLoadShedTargetLatency(latency time.Duration) LoadShedOption {
+	return func(s *loadShedder) {
+		s.targetLatency = latency
+	}
+}
+
+// WithLoadShedBackoff sets the ratio the concurrency limit is scaled by when a request exceeds the target latency.
+// The default is 0.9.
+func WithLoadShedBackoff(ratio float64) LoadShedOption {
+	return func(s *loadShedder) {
+		s.backoff = ratio
+	}
+}
+
+// WithLowPriorityShare sets the share of the concurrency limit that low priority requests may use. The default is
+// 0.5.
+func WithLowPriorityShare(share float64) LoadShedOption {
+	return func(s *loadShedder) {
+		s.lowPriorityShare = share
+	}
+}
+
+// WithRequestPriority sets the classifier used to prioritise requests. The default is DefaultRequestPriority with no
+// health check paths.
+func WithRequestPriority(priorityFunc RequestPriorityFunc) LoadShedOption {
+	return func(s *loadShedder) {
+		s.priorityFunc = priorityFunc
+	}
+}
+
+// WithLoadShedRetryAfter sets the Retry-After sent with rejected requests. The default is one second.
+func WithLoadShedRetryAfter(retryAfter time.Duration) LoadShedOption {
+	return func(s *loadShedder) {
+		s.retryAfter = retryAfter
+	}
+}
+
+// WithLoadShedClock sets the clock the latency of the requests is measured with. The default is the system clock.
+func WithLoadShedClock(clock Clock) LoadShedOption {
+	return func(s *loadShedder) {
+		s.clock = clock
+	}
+}
+
+func newLoadShedder(opts ...LoadShedOption) *loadShedder {
+	s := &loadShedder{
+		limit:            20,
+		minLimit:         1,
+		maxLimit:         1000,
+		targetLatency:    500 * time.Millisecond,
+		backoff:          0.9,
+		lowPriorityShare: 0.5,
+		priorityFunc:     DefaultRequestPriority(),
+		retryAfter:       time.Second,
+		clock:            systemClock{},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// acquire admits a request of the given priority, returning false if it should be shed.
+func (s *loadShedder) acquire(priority RequestPriority) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if priority < PriorityCritical {
+		capacity := s.limit
+		if priority == PriorityLow {
+			capacity *= s.lowPriorityShare
+		}
+
+		if float64(s.inFlight) >= capacity {
+			return false
+		}
+	}
+
+	s.inFlight++
+	return true
+}
+
+// release records the latency of a completed request and adapts the limit.
+func (s *loadShedder) release(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inFlight := s.inFlight
+	s.inFlight--
+
+	switch {
+	case latency > s.targetLatency:
+		s.limit = max(s.limit*s.backoff, s.minLimit)
+	case float64(inFlight)*2 >= s.limit:
+		// Only grow the limit while it is being used, otherwise a quiet period would leave it far above what the
+		// server can handle.
+		s.limit = min(s.limit+1, s.maxLimit)
+	}
+}
+
+// LoadShed returns a middleware that sheds load when the server is overloaded. The number of requests in flight is
+// capped by a limit that adapts to the latency of the requests served, and requests over the limit are rejected with
+// a 503 and a Retry-After header.
+//
+// Requests are prioritised by the classifier set with WithRequestPriority: low priority requests are shed before
+// normal ones, and critical requests are always served.
+func LoadShed(opts ...LoadShedOption) MiddlewareFunc {
+	s := newLoadShedder(opts...)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !s.acquire(s.priorityFunc(r)) {
+				if s.retryAfter > 0 {
+					w.Header().Set(HeaderRetryAfter, formatDeltaSeconds(s.retryAfter))
+				}
+				ServiceUnavailableHandler().ServeHTTP(w, r)
+				return
+			}
+
+			start := s.clock.Now()
+			defer func() {
+				s.release(s.clock.Now().Sub(start))
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package uhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jacobbrewer1/uhttp/uhttptest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultRequestPriority(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		forwarded string
+		want      RequestPriority
+	}{
+		{
+			name:      "health check",
+			path:      "/health",
+			forwarded: "203.0.113.1",
+			want:      PriorityCritical,
+		},
+		{
+			name: "internal",
+			path: "/users",
+			want: PriorityCritical,
+		},
+		{
+			name:      "external",
+			path:      "/users",
+			forwarded: "203.0.113.1",
+			want:      PriorityNormal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, http.NoBody)
+			if tt.forwarded != "" {
+				r.Header.Set(headerForwardedFor, tt.forwarded)
+			}
+
+			require.Equal(t, tt.want, DefaultRequestPriority("/health")(r))
+		})
+	}
+}
+
+func TestLoadShedder_Acquire(t *testing.T) {
+	s := newLoadShedder(WithLoadShedLimits(4, 1, 10))
+
+	// Low priority requests may use half of the limit.
+	require.True(t, s.acquire(PriorityLow))
+	require.True(t, s.acquire(PriorityLow))
+	require.False(t, s.acquire(PriorityLow))
+
+	require.True(t, s.acquire(PriorityNormal))
+	require.True(t, s.acquire(PriorityNormal))
+	require.False(t, s.acquire(PriorityNormal))
+
+	// Critical requests are never shed.
+	require.True(t, s.acquire(PriorityCritical))
+	require.Equal(t, 5, s.inFlight)
+}
+
+func TestLoadShedder_Release(t *testing.T) {
+	s := newLoadShedder(
+		WithLoadShedLimits(10, 2, 11),
+		WithLoadShedTargetLatency(100*time.Millisecond),
+		WithLoadShedBackoff(0.5),
+	)
+
+	// A fast request on an idle server leaves the limit alone.
+	require.True(t, s.acquire(PriorityNormal))
+	s.release(time.Millisecond)
+	require.InDelta(t, 10, s.limit, 0)
+
+	// Fast requests on a busy server raise the limit, up to the maximum.
+	for range 5 {
+		require.True(t, s.acquire(PriorityNormal))
+	}
+	s.release(time.Millisecond)
+	require.InDelta(t, 11, s.limit, 0)
+	s.release(time.Millisecond)
+	require.InDelta(t, 11, s.limit, 0)
+
+	// Slow requests lower the limit, down to the minimum.
+	s.release(time.Second)
+	require.InDelta(t, 5.5, s.limit, 0)
+	s.release(time.Second)
+	require.InDelta(t, 2.75, s.limit, 0)
+	s.release(time.Second)
+	require.InDelta(t, 2, s.limit, 0)
+	require.Zero(t, s.inFlight)
+}
+
+func TestLoadShed(t *testing.T) {
+	unblock := make(chan struct{})
+	started := make(chan struct{})
+
+	handler := WrapHandler(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-unblock
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}, LoadShed(
+		WithLoadShedLimits(1, 1, 1),
+		WithRequestPriority(DefaultRequestPriority("/health")),
+		WithLoadShedRetryAfter(2*time.Second),
+	))
+
+	external := func(path string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, path, http.NoBody)
+		r.Header.Set(headerForwardedFor, "203.0.113.1")
+		return r
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(httptest.NewRecorder(), external("/slow"))
+	}()
+	<-started
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, external("/users"))
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	require.Equal(t, "2", w.Header().Get(HeaderRetryAfter))
+
+	resp := new(HTTPError)
+	require.NoError(t, DecodeJSON(w.Result().Body, resp))
+	require.Equal(t, http.StatusServiceUnavailable, resp.Status)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, external("/health"))
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	close(unblock)
+	<-done
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, external("/users"))
+	require.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestLoadShed_Clock(t *testing.T) {
+	clock := uhttptest.NewFakeClock(time.Unix(1_700_000_000, 0))
+	unblock := make(chan struct{})
+	started := make(chan struct{})
+
+	handler := WrapHandler(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			clock.Advance(time.Second)
+		case "/block":
+			close(started)
+			<-unblock
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}, LoadShed(
+		WithLoadShedLimits(2, 1, 2),
+		WithLoadShedBackoff(0.5),
+		WithLoadShedClock(clock),
+	))
+
+	external := func(path string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, path, http.NoBody)
+		r.Header.Set(headerForwardedFor, "203.0.113.1")
+		return r
+	}
+
+	// The request takes a second by the clock, so the limit is halved.
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, external("/slow"))
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(httptest.NewRecorder(), external("/block"))
+	}()
+	<-started
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, external("/users"))
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	close(unblock)
+	<-done
+}
@@ -8,6 +8,13 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+const (
+	// responseSizeSent and responseSizeUncompressed are the values of the size label observed by
+	// InstrumentCompressedResponseSize.
+	responseSizeSent         = "sent"
+	responseSizeUncompressed = "uncompressed"
+)
+
 // MiddlewareFunc is a middleware function.
 type MiddlewareFunc = mux.MiddlewareFunc
 
@@ -38,3 +45,43 @@ func InstrumentResponseSize(metric *prometheus.HistogramVec) MiddlewareFunc {
 		return promhttp.InstrumentHandlerResponseSize(metric, next)
 	}
 }
+
+// InstrumentCompressedResponseSize instruments a handler with the response size both as sent to the client and before
+// compression, updating the supplied histogram. It must wrap the Compress middleware. The histogram is partitioned by
+// the "size" label alone: every response is observed once as "sent" and once as "uncompressed", and the two are the
+// same for responses that are not compressed.
+//
+// Unlike InstrumentResponseSize, it reads the sizes from the *ResponseWriter the response is written through, adding
+// one if there is none.
+func InstrumentCompressedResponseSize(metric *prometheus.HistogramVec) MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := responseWriterOf(w)
+			if rw == nil {
+				rw = &ResponseWriter{
+					ResponseWriter:    w,
+					clock:             systemClock{},
+					defaultStatusCode: http.StatusOK,
+				}
+				// The writer is hidden from the handler, which would otherwise write its defaults to it.
+				w = &sizeWriter{rw}
+			}
+
+			next.ServeHTTP(w, r)
+
+			metric.WithLabelValues(responseSizeSent).Observe(float64(rw.BytesWritten()))
+			metric.WithLabelValues(responseSizeUncompressed).Observe(float64(rw.UncompressedBytesWritten()))
+		})
+	}
+}
+
+// sizeWriter passes the response through to a *ResponseWriter that records its size, which Compress finds by
+// unwrapping it.
+type sizeWriter struct {
+	*ResponseWriter
+}
+
+// Unwrap returns the *ResponseWriter recording the size of the response.
+func (w *sizeWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
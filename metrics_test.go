@@ -0,0 +1,56 @@
+package uhttp
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrumentCompressedResponseSize(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	metric := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "response_size_bytes",
+		Help:    "Size of the responses",
+		Buckets: []float64{1000},
+	}, []string{"size"})
+	reg.MustRegister(metric)
+
+	body := strings.Repeat("compressible ", 500)
+	handler := WrapHandler(func(w http.ResponseWriter, _ *http.Request) {
+		// The handler does not see the *ResponseWriter added by the instrumentation.
+		_, ok := w.(*ResponseWriter)
+		require.False(t, ok)
+
+		w.Header().Set(HeaderContentType, "text/plain")
+		_, _ = w.Write([]byte(body))
+	}, Compress(), InstrumentCompressedResponseSize(metric))
+
+	r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	r.Header.Set(HeaderAcceptEncoding, "gzip")
+	compressed := httptest.NewRecorder()
+	handler.ServeHTTP(compressed, r)
+	require.Equal(t, EncodingGzip, compressed.Header().Get(HeaderContentEncoding))
+
+	r.Header.Del(HeaderAcceptEncoding)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	err := testutil.GatherAndCompare(reg, strings.NewReader(fmt.Sprintf(`
+# HELP response_size_bytes Size of the responses
+# TYPE response_size_bytes histogram
+response_size_bytes_bucket{size="sent",le="1000"} 1
+response_size_bytes_bucket{size="sent",le="+Inf"} 2
+response_size_bytes_sum{size="sent"} %d
+response_size_bytes_count{size="sent"} 2
+response_size_bytes_bucket{size="uncompressed",le="1000"} 0
+response_size_bytes_bucket{size="uncompressed",le="+Inf"} 2
+response_size_bytes_sum{size="uncompressed"} %d
+response_size_bytes_count{size="uncompressed"} 2
+`, compressed.Body.Len()+len(body), 2*len(body))), "response_size_bytes")
+	require.NoError(t, err)
+}
@@ -0,0 +1,188 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package uhttp
+
+import (
+	context "context"
+	time "time"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockBanStore is an autogenerated mock type for the BanStore type
+type MockBanStore struct {
+	mock.Mock
+}
+
+// AddStrike provides a mock function with given fields: ctx, key, now, ttl
+func (_m *MockBanStore) AddStrike(ctx context.Context, key string, now time.Time, ttl time.Duration) (int, error) {
+	ret := _m.Called(ctx, key, now, ttl)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddStrike")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time, time.Duration) (int, error)); ok {
+		return rf(ctx, key, now, ttl)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time, time.Duration) int); ok {
+		r0 = rf(ctx, key, now, ttl)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Time, time.Duration) error); ok {
+		r1 = rf(ctx, key, now, ttl)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AddViolation provides a mock function with given fields: ctx, key, now, window
+func (_m *MockBanStore) AddViolation(ctx context.Context, key string, now time.Time, window time.Duration) (int, error) {
+	ret := _m.Called(ctx, key, now, window)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddViolation")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time, time.Duration) (int, error)); ok {
+		return rf(ctx, key, now, window)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time, time.Duration) int); ok {
+		r0 = rf(ctx, key, now, window)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Time, time.Duration) error); ok {
+		r1 = rf(ctx, key, now, window)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteBan provides a mock function with given fields: ctx, key
+func (_m *MockBanStore) DeleteBan(ctx context.Context, key string) error {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteBan")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetBan provides a mock function with given fields: ctx, key, now
+func (_m *MockBanStore) GetBan(ctx context.Context, key string, now time.Time) (*Ban, bool, error) {
+	ret := _m.Called(ctx, key, now)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBan")
+	}
+
+	var r0 *Ban
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) (*Ban, bool, error)); ok {
+		return rf(ctx, key, now)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) *Ban); ok {
+		r0 = rf(ctx, key, now)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*Ban)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Time) bool); ok {
+		r1 = rf(ctx, key, now)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string, time.Time) error); ok {
+		r2 = rf(ctx, key, now)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ListBans provides a mock function with given fields: ctx, now
+func (_m *MockBanStore) ListBans(ctx context.Context, now time.Time) ([]*Ban, error) {
+	ret := _m.Called(ctx, now)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListBans")
+	}
+
+	var r0 []*Ban
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) ([]*Ban, error)); ok {
+		return rf(ctx, now)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) []*Ban); ok {
+		r0 = rf(ctx, now)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*Ban)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetBan provides a mock function with given fields: ctx, ban, now
+func (_m *MockBanStore) SetBan(ctx context.Context, ban *Ban, now time.Time) error {
+	ret := _m.Called(ctx, ban, now)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetBan")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *Ban, time.Time) error); ok {
+		r0 = rf(ctx, ban, now)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewMockBanStore creates a new instance of MockBanStore. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockBanStore(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockBanStore {
+	mock := &MockBanStore{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,64 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package uhttp
+
+import (
+	io "io"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockCodec is an autogenerated mock type for the Codec type
+type MockCodec struct {
+	mock.Mock
+}
+
+// Decode provides a mock function with given fields: r, v
+func (_m *MockCodec) Decode(r io.Reader, v interface{}) error {
+	ret := _m.Called(r, v)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Decode")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(io.Reader, interface{}) error); ok {
+		r0 = rf(r, v)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Encode provides a mock function with given fields: w, v
+func (_m *MockCodec) Encode(w io.Writer, v interface{}) error {
+	ret := _m.Called(w, v)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Encode")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(io.Writer, interface{}) error); ok {
+		r0 = rf(w, v)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewMockCodec creates a new instance of MockCodec. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockCodec(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockCodec {
+	mock := &MockCodec{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,29 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package uhttp
+
+import mock "github.com/stretchr/testify/mock"
+
+// MockCompressionOption is an autogenerated mock type for the CompressionOption type
+type MockCompressionOption struct {
+	mock.Mock
+}
+
+// Execute provides a mock function with given fields: _a0
+func (_m *MockCompressionOption) Execute(_a0 *compression) {
+	_m.Called(_a0)
+}
+
+// NewMockCompressionOption creates a new instance of MockCompressionOption. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockCompressionOption(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockCompressionOption {
+	mock := &MockCompressionOption{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
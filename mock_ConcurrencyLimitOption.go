@@ -0,0 +1,29 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package uhttp
+
+import mock "github.com/stretchr/testify/mock"
+
+// MockConcurrencyLimitOption is an autogenerated mock type for the ConcurrencyLimitOption type
+type MockConcurrencyLimitOption struct {
+	mock.Mock
+}
+
+// Execute provides a mock function with given fields: _a0
+func (_m *MockConcurrencyLimitOption) Execute(_a0 *concurrencyLimitMiddleware) {
+	_m.Called(_a0)
+}
+
+// NewMockConcurrencyLimitOption creates a new instance of MockConcurrencyLimitOption. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockConcurrencyLimitOption(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockConcurrencyLimitOption {
+	mock := &MockConcurrencyLimitOption{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
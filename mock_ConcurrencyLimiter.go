@@ -0,0 +1,65 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package uhttp
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockConcurrencyLimiter is an autogenerated mock type for the ConcurrencyLimiter type
+type MockConcurrencyLimiter struct {
+	mock.Mock
+}
+
+// Acquire provides a mock function with given fields: ctx, key
+func (_m *MockConcurrencyLimiter) Acquire(ctx context.Context, key string) (func(), bool, error) {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Acquire")
+	}
+
+	var r0 func()
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (func(), bool, error)); ok {
+		return rf(ctx, key)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) func()); ok {
+		r0 = rf(ctx, key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(func())
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) bool); ok {
+		r1 = rf(ctx, key)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string) error); ok {
+		r2 = rf(ctx, key)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// NewMockConcurrencyLimiter creates a new instance of MockConcurrencyLimiter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockConcurrencyLimiter(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockConcurrencyLimiter {
+	mock := &MockConcurrencyLimiter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,29 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package uhttp
+
+import mock "github.com/stretchr/testify/mock"
+
+// MockDecodeOption is an autogenerated mock type for the DecodeOption type
+type MockDecodeOption struct {
+	mock.Mock
+}
+
+// Execute provides a mock function with given fields: _a0
+func (_m *MockDecodeOption) Execute(_a0 *decoder) {
+	_m.Called(_a0)
+}
+
+// NewMockDecodeOption creates a new instance of MockDecodeOption. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockDecodeOption(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockDecodeOption {
+	mock := &MockDecodeOption{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
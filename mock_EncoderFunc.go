@@ -0,0 +1,46 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package uhttp
+
+import (
+	http "net/http"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockEncoderFunc is an autogenerated mock type for the EncoderFunc type
+type MockEncoderFunc struct {
+	mock.Mock
+}
+
+// Execute provides a mock function with given fields: w, r, status, v
+func (_m *MockEncoderFunc) Execute(w http.ResponseWriter, r *http.Request, status int, v interface{}) error {
+	ret := _m.Called(w, r, status, v)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Execute")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(http.ResponseWriter, *http.Request, int, interface{}) error); ok {
+		r0 = rf(w, r, status, v)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewMockEncoderFunc creates a new instance of MockEncoderFunc. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockEncoderFunc(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockEncoderFunc {
+	mock := &MockEncoderFunc{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,87 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package uhttp
+
+import (
+	context "context"
+	time "time"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockEventFollower is an autogenerated mock type for the EventFollower type
+type MockEventFollower struct {
+	mock.Mock
+}
+
+// Follow provides a mock function with given fields: ctx, topic, lastID, timeout
+func (_m *MockEventFollower) Follow(ctx context.Context, topic string, lastID string, timeout time.Duration) ([]*Event, error) {
+	ret := _m.Called(ctx, topic, lastID, timeout)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Follow")
+	}
+
+	var r0 []*Event
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Duration) ([]*Event, error)); ok {
+		return rf(ctx, topic, lastID, timeout)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Duration) []*Event); ok {
+		r0 = rf(ctx, topic, lastID, timeout)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*Event)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, time.Duration) error); ok {
+		r1 = rf(ctx, topic, lastID, timeout)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LastID provides a mock function with given fields: ctx, topic
+func (_m *MockEventFollower) LastID(ctx context.Context, topic string) (string, error) {
+	ret := _m.Called(ctx, topic)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LastID")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
+		return rf(ctx, topic)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = rf(ctx, topic)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, topic)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewMockEventFollower creates a new instance of MockEventFollower. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockEventFollower(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockEventFollower {
+	mock := &MockEventFollower{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
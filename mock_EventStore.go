@@ -0,0 +1,76 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package uhttp
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockEventStore is an autogenerated mock type for the EventStore type
+type MockEventStore struct {
+	mock.Mock
+}
+
+// Append provides a mock function with given fields: ctx, topic, ev
+func (_m *MockEventStore) Append(ctx context.Context, topic string, ev *Event) error {
+	ret := _m.Called(ctx, topic, ev)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Append")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *Event) error); ok {
+		r0 = rf(ctx, topic, ev)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Since provides a mock function with given fields: ctx, topic, lastID
+func (_m *MockEventStore) Since(ctx context.Context, topic string, lastID string) ([]*Event, error) {
+	ret := _m.Called(ctx, topic, lastID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Since")
+	}
+
+	var r0 []*Event
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) ([]*Event, error)); ok {
+		return rf(ctx, topic, lastID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []*Event); ok {
+		r0 = rf(ctx, topic, lastID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*Event)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, topic, lastID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewMockEventStore creates a new instance of MockEventStore. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockEventStore(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockEventStore {
+	mock := &MockEventStore{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
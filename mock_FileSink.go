@@ -0,0 +1,47 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package uhttp
+
+import (
+	context "context"
+	io "io"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockFileSink is an autogenerated mock type for the FileSink type
+type MockFileSink struct {
+	mock.Mock
+}
+
+// Store provides a mock function with given fields: ctx, file, content
+func (_m *MockFileSink) Store(ctx context.Context, file *FormFile, content io.Reader) error {
+	ret := _m.Called(ctx, file, content)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Store")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *FormFile, io.Reader) error); ok {
+		r0 = rf(ctx, file, content)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewMockFileSink creates a new instance of MockFileSink. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockFileSink(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockFileSink {
+	mock := &MockFileSink{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
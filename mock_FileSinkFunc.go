@@ -0,0 +1,47 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package uhttp
+
+import (
+	context "context"
+	io "io"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockFileSinkFunc is an autogenerated mock type for the FileSinkFunc type
+type MockFileSinkFunc struct {
+	mock.Mock
+}
+
+// Execute provides a mock function with given fields: ctx, file, content
+func (_m *MockFileSinkFunc) Execute(ctx context.Context, file *FormFile, content io.Reader) error {
+	ret := _m.Called(ctx, file, content)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Execute")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *FormFile, io.Reader) error); ok {
+		r0 = rf(ctx, file, content)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewMockFileSinkFunc creates a new instance of MockFileSinkFunc. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockFileSinkFunc(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockFileSinkFunc {
+	mock := &MockFileSinkFunc{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
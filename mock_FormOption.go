@@ -0,0 +1,29 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package uhttp
+
+import mock "github.com/stretchr/testify/mock"
+
+// MockFormOption is an autogenerated mock type for the FormOption type
+type MockFormOption struct {
+	mock.Mock
+}
+
+// Execute provides a mock function with given fields: _a0
+func (_m *MockFormOption) Execute(_a0 *formDecoder) {
+	_m.Called(_a0)
+}
+
+// NewMockFormOption creates a new instance of MockFormOption. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockFormOption(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockFormOption {
+	mock := &MockFormOption{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
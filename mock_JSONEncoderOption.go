@@ -0,0 +1,29 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package uhttp
+
+import mock "github.com/stretchr/testify/mock"
+
+// MockJSONEncoderOption is an autogenerated mock type for the JSONEncoderOption type
+type MockJSONEncoderOption struct {
+	mock.Mock
+}
+
+// Execute provides a mock function with given fields: _a0
+func (_m *MockJSONEncoderOption) Execute(_a0 *jsonEncoder) {
+	_m.Called(_a0)
+}
+
+// NewMockJSONEncoderOption creates a new instance of MockJSONEncoderOption. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockJSONEncoderOption(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockJSONEncoderOption {
+	mock := &MockJSONEncoderOption{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,42 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package uhttp
+
+import mock "github.com/stretchr/testify/mock"
+
+// MockKeyCounter is an autogenerated mock type for the KeyCounter type
+type MockKeyCounter struct {
+	mock.Mock
+}
+
+// Len provides a mock function with no fields
+func (_m *MockKeyCounter) Len() int {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Len")
+	}
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func() int); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	return r0
+}
+
+// NewMockKeyCounter creates a new instance of MockKeyCounter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockKeyCounter(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockKeyCounter {
+	mock := &MockKeyCounter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
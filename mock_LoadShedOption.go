@@ -0,0 +1,29 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package uhttp
+
+import mock "github.com/stretchr/testify/mock"
+
+// MockLoadShedOption is an autogenerated mock type for the LoadShedOption type
+type MockLoadShedOption struct {
+	mock.Mock
+}
+
+// Execute provides a mock function with given fields: _a0
+func (_m *MockLoadShedOption) Execute(_a0 *loadShedder) {
+	_m.Called(_a0)
+}
+
+// NewMockLoadShedOption creates a new instance of MockLoadShedOption. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockLoadShedOption(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockLoadShedOption {
+	mock := &MockLoadShedOption{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
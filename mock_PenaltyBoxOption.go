@@ -0,0 +1,29 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package uhttp
+
+import mock "github.com/stretchr/testify/mock"
+
+// MockPenaltyBoxOption is an autogenerated mock type for the PenaltyBoxOption type
+type MockPenaltyBoxOption struct {
+	mock.Mock
+}
+
+// Execute provides a mock function with given fields: _a0
+func (_m *MockPenaltyBoxOption) Execute(_a0 *PenaltyBox) {
+	_m.Called(_a0)
+}
+
+// NewMockPenaltyBoxOption creates a new instance of MockPenaltyBoxOption. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockPenaltyBoxOption(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockPenaltyBoxOption {
+	mock := &MockPenaltyBoxOption{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,46 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package uhttp
+
+import (
+	http "net/http"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockRateLimitCostFunc is an autogenerated mock type for the RateLimitCostFunc type
+type MockRateLimitCostFunc struct {
+	mock.Mock
+}
+
+// Execute provides a mock function with given fields: r
+func (_m *MockRateLimitCostFunc) Execute(r *http.Request) int {
+	ret := _m.Called(r)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Execute")
+	}
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(*http.Request) int); ok {
+		r0 = rf(r)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	return r0
+}
+
+// NewMockRateLimitCostFunc creates a new instance of MockRateLimitCostFunc. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockRateLimitCostFunc(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockRateLimitCostFunc {
+	mock := &MockRateLimitCostFunc{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,29 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package uhttp
+
+import mock "github.com/stretchr/testify/mock"
+
+// MockRateLimitMiddlewareOption is an autogenerated mock type for the RateLimitMiddlewareOption type
+type MockRateLimitMiddlewareOption struct {
+	mock.Mock
+}
+
+// Execute provides a mock function with given fields: _a0
+func (_m *MockRateLimitMiddlewareOption) Execute(_a0 *rateLimitMiddleware) {
+	_m.Called(_a0)
+}
+
+// NewMockRateLimitMiddlewareOption creates a new instance of MockRateLimitMiddlewareOption. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockRateLimitMiddlewareOption(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockRateLimitMiddlewareOption {
+	mock := &MockRateLimitMiddlewareOption{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
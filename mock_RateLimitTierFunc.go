@@ -0,0 +1,46 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package uhttp
+
+import (
+	http "net/http"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockRateLimitTierFunc is an autogenerated mock type for the RateLimitTierFunc type
+type MockRateLimitTierFunc struct {
+	mock.Mock
+}
+
+// Execute provides a mock function with given fields: r
+func (_m *MockRateLimitTierFunc) Execute(r *http.Request) string {
+	ret := _m.Called(r)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Execute")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(*http.Request) string); ok {
+		r0 = rf(r)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// NewMockRateLimitTierFunc creates a new instance of MockRateLimitTierFunc. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockRateLimitTierFunc(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockRateLimitTierFunc {
+	mock := &MockRateLimitTierFunc{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -2,7 +2,11 @@
 
 package uhttp
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
 
 // MockRateLimiter is an autogenerated mock type for the RateLimiter type
 type MockRateLimiter struct {
@@ -27,6 +31,54 @@ func (_m *MockRateLimiter) Allow(key string) bool {
 	return r0
 }
 
+// AllowN provides a mock function with given fields: key, n
+func (_m *MockRateLimiter) AllowN(key string, n int) bool {
+	ret := _m.Called(key, n)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AllowN")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string, int) bool); ok {
+		r0 = rf(key, n)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// Take provides a mock function with given fields: ctx, key, cost
+func (_m *MockRateLimiter) Take(ctx context.Context, key string, cost int) (*RateLimitDecision, error) {
+	ret := _m.Called(ctx, key, cost)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Take")
+	}
+
+	var r0 *RateLimitDecision
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) (*RateLimitDecision, error)); ok {
+		return rf(ctx, key, cost)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) *RateLimitDecision); ok {
+		r0 = rf(ctx, key, cost)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*RateLimitDecision)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, key, cost)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewMockRateLimiter creates a new instance of MockRateLimiter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockRateLimiter(t interface {
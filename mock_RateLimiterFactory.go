@@ -0,0 +1,44 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package uhttp
+
+import mock "github.com/stretchr/testify/mock"
+
+// MockRateLimiterFactory is an autogenerated mock type for the RateLimiterFactory type
+type MockRateLimiterFactory struct {
+	mock.Mock
+}
+
+// Execute provides a mock function with given fields: policy
+func (_m *MockRateLimiterFactory) Execute(policy RateLimitPolicy) RateLimiter {
+	ret := _m.Called(policy)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Execute")
+	}
+
+	var r0 RateLimiter
+	if rf, ok := ret.Get(0).(func(RateLimitPolicy) RateLimiter); ok {
+		r0 = rf(policy)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(RateLimiter)
+		}
+	}
+
+	return r0
+}
+
+// NewMockRateLimiterFactory creates a new instance of MockRateLimiterFactory. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockRateLimiterFactory(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockRateLimiterFactory {
+	mock := &MockRateLimiterFactory{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,29 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package uhttp
+
+import mock "github.com/stretchr/testify/mock"
+
+// MockRedisBanStoreOption is an autogenerated mock type for the RedisBanStoreOption type
+type MockRedisBanStoreOption struct {
+	mock.Mock
+}
+
+// Execute provides a mock function with given fields: _a0
+func (_m *MockRedisBanStoreOption) Execute(_a0 *redisBanStore) {
+	_m.Called(_a0)
+}
+
+// NewMockRedisBanStoreOption creates a new instance of MockRedisBanStoreOption. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockRedisBanStoreOption(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockRedisBanStoreOption {
+	mock := &MockRedisBanStoreOption{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,29 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package uhttp
+
+import mock "github.com/stretchr/testify/mock"
+
+// MockRedisConcurrencyLimiterOption is an autogenerated mock type for the RedisConcurrencyLimiterOption type
+type MockRedisConcurrencyLimiterOption struct {
+	mock.Mock
+}
+
+// Execute provides a mock function with given fields: _a0
+func (_m *MockRedisConcurrencyLimiterOption) Execute(_a0 *redisConcurrencyLimiter) {
+	_m.Called(_a0)
+}
+
+// NewMockRedisConcurrencyLimiterOption creates a new instance of MockRedisConcurrencyLimiterOption. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockRedisConcurrencyLimiterOption(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockRedisConcurrencyLimiterOption {
+	mock := &MockRedisConcurrencyLimiterOption{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
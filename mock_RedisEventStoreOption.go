@@ -0,0 +1,29 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package uhttp
+
+import mock "github.com/stretchr/testify/mock"
+
+// MockRedisEventStoreOption is an autogenerated mock type for the RedisEventStoreOption type
+type MockRedisEventStoreOption struct {
+	mock.Mock
+}
+
+// Execute provides a mock function with given fields: _a0
+func (_m *MockRedisEventStoreOption) Execute(_a0 *redisEventStore) {
+	_m.Called(_a0)
+}
+
+// NewMockRedisEventStoreOption creates a new instance of MockRedisEventStoreOption. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockRedisEventStoreOption(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockRedisEventStoreOption {
+	mock := &MockRedisEventStoreOption{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
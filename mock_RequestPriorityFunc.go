@@ -0,0 +1,46 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package uhttp
+
+import (
+	http "net/http"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockRequestPriorityFunc is an autogenerated mock type for the RequestPriorityFunc type
+type MockRequestPriorityFunc struct {
+	mock.Mock
+}
+
+// Execute provides a mock function with given fields: r
+func (_m *MockRequestPriorityFunc) Execute(r *http.Request) RequestPriority {
+	ret := _m.Called(r)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Execute")
+	}
+
+	var r0 RequestPriority
+	if rf, ok := ret.Get(0).(func(*http.Request) RequestPriority); ok {
+		r0 = rf(r)
+	} else {
+		r0 = ret.Get(0).(RequestPriority)
+	}
+
+	return r0
+}
+
+// NewMockRequestPriorityFunc creates a new instance of MockRequestPriorityFunc. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockRequestPriorityFunc(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockRequestPriorityFunc {
+	mock := &MockRequestPriorityFunc{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
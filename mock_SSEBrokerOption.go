@@ -0,0 +1,29 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package uhttp
+
+import mock "github.com/stretchr/testify/mock"
+
+// MockSSEBrokerOption is an autogenerated mock type for the SSEBrokerOption type
+type MockSSEBrokerOption struct {
+	mock.Mock
+}
+
+// Execute provides a mock function with given fields: _a0
+func (_m *MockSSEBrokerOption) Execute(_a0 *SSEBroker) {
+	_m.Called(_a0)
+}
+
+// NewMockSSEBrokerOption creates a new instance of MockSSEBrokerOption. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockSSEBrokerOption(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockSSEBrokerOption {
+	mock := &MockSSEBrokerOption{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,29 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package uhttp
+
+import mock "github.com/stretchr/testify/mock"
+
+// MockStreamOption is an autogenerated mock type for the StreamOption type
+type MockStreamOption struct {
+	mock.Mock
+}
+
+// Execute provides a mock function with given fields: _a0
+func (_m *MockStreamOption) Execute(_a0 *streamer) {
+	_m.Called(_a0)
+}
+
+// NewMockStreamOption creates a new instance of MockStreamOption. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockStreamOption(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockStreamOption {
+	mock := &MockStreamOption{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,97 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package uhttp
+
+import (
+	io "io"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// mockCompressor is an autogenerated mock type for the compressor type
+type mockCompressor struct {
+	mock.Mock
+}
+
+// Close provides a mock function with no fields
+func (_m *mockCompressor) Close() error {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Close")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Flush provides a mock function with no fields
+func (_m *mockCompressor) Flush() error {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Flush")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Reset provides a mock function with given fields: w
+func (_m *mockCompressor) Reset(w io.Writer) {
+	_m.Called(w)
+}
+
+// Write provides a mock function with given fields: p
+func (_m *mockCompressor) Write(p []byte) (int, error) {
+	ret := _m.Called(p)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Write")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func([]byte) (int, error)); ok {
+		return rf(p)
+	}
+	if rf, ok := ret.Get(0).(func([]byte) int); ok {
+		r0 = rf(p)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func([]byte) error); ok {
+		r1 = rf(p)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// newMockCompressor creates a new instance of mockCompressor. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func newMockCompressor(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *mockCompressor {
+	mock := &mockCompressor{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
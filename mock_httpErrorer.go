@@ -0,0 +1,44 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package uhttp
+
+import mock "github.com/stretchr/testify/mock"
+
+// mockHttpErrorer is an autogenerated mock type for the httpErrorer type
+type mockHttpErrorer struct {
+	mock.Mock
+}
+
+// HTTPError provides a mock function with no fields
+func (_m *mockHttpErrorer) HTTPError() *HTTPError {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for HTTPError")
+	}
+
+	var r0 *HTTPError
+	if rf, ok := ret.Get(0).(func() *HTTPError); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*HTTPError)
+		}
+	}
+
+	return r0
+}
+
+// newMockHttpErrorer creates a new instance of mockHttpErrorer. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func newMockHttpErrorer(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *mockHttpErrorer {
+	mock := &mockHttpErrorer{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
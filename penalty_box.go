@@ -0,0 +1,256 @@
+package uhttp
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+var (
+	// errMissingBanKey is returned by the ban admin handler when no key is given to unban.
+	errMissingBanKey = errors.New("missing key query parameter")
+)
+
+const (
+	// banKeyQueryParam is the query parameter naming the key to unban.
+	banKeyQueryParam = "key"
+)
+
+// PenaltyBoxOption configures the penalty box.
+type PenaltyBoxOption func(*PenaltyBox)
+
+// PenaltyBox is a RateLimiter that bans the keys that keep violating the limit of the rate limiter it wraps. Once a
+// key has been denied threshold times within the violation window it is banned, and every request it makes is denied
+// until the ban expires. Each further ban doubles in length, up to the maximum ban.
+type PenaltyBox struct {
+	limiter RateLimiter
+	store   BanStore
+	clock   Clock
+	l       *slog.Logger
+
+	// threshold is the number of violations within the window that bans the key.
+	threshold int
+
+	// window is the period the violations are counted over.
+	window time.Duration
+
+	// baseBan is the length of the first ban.
+	baseBan time.Duration
+
+	// maxBan caps the length of the escalating bans.
+	maxBan time.Duration
+
+	// strikeTTL is how long a key must go without being banned before its bans stop escalating.
+	strikeTTL time.Duration
+}
+
+// WithBanThreshold bans a key once it has violated the limit the given number of times within the window. The default
+// is 10 violations within a minute.
+func WithBanThreshold(violations int, window time.Duration) PenaltyBoxOption {
+	return func(p *PenaltyBox) {
+		p.threshold = violations
+		p.window = window
+	}
+}
+
+// WithBanDuration sets the length of the first ban and the maximum length the following bans escalate to. The default
+// is one minute, escalating up to an hour.
+func WithBanDuration(base, maxBan time.Duration) PenaltyBoxOption {
+	return func(p *PenaltyBox) {
+		p.baseBan = base
+		p.maxBan = maxBan
+	}
+}
+
+// WithBanStrikeTTL sets how long a key must go without being banned before its next ban starts again at the base
+// length. The default is a day.
+func WithBanStrikeTTL(ttl time.Duration) PenaltyBoxOption {
+	return func(p *PenaltyBox) {
+		p.strikeTTL = ttl
+	}
+}
+
+// WithPenaltyBoxClock sets the clock the penalty box tells the time with. The default is the system clock.
+func WithPenaltyBoxClock(clock Clock) PenaltyBoxOption {
+	return func(p *PenaltyBox) {
+		p.clock = clock
+	}
+}
+
+// WithPenaltyBoxLogger sets the logger for the penalty box.
+func WithPenaltyBoxLogger(l *slog.Logger) PenaltyBoxOption {
+	return func(p *PenaltyBox) {
+		p.l = l
+	}
+}
+
+// NewPenaltyBox creates a new penalty box around the rate limiter, holding the bans in the store.
+func NewPenaltyBox(limiter RateLimiter, store BanStore, opts ...PenaltyBoxOption) *PenaltyBox {
+	p := &PenaltyBox{
+		limiter:   limiter,
+		store:     store,
+		clock:     systemClock{},
+		threshold: 10,
+		window:    time.Minute,
+		baseBan:   time.Minute,
+		maxBan:    time.Hour,
+		strikeTTL: 24 * time.Hour,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// Allow returns true if the request is allowed.
+func (p *PenaltyBox) Allow(key string) bool {
+	return p.AllowN(key, 1)
+}
+
+// AllowN returns true if a request costing n units of the quota is allowed.
+func (p *PenaltyBox) AllowN(key string, n int) bool {
+	decision, err := p.Take(context.Background(), key, n)
+	if err != nil {
+		p.log(slog.LevelError, "failed to check rate limit", slog.String(loggingKeyKey, key), slog.String(loggingKeyError, err.Error()))
+		return false
+	}
+	return decision.Allowed
+}
+
+// Take denies the request if the key is banned, and otherwise checks it against the rate limiter, banning the key if
+// it has violated the limit too often. A banned key is denied with a Retry-After of the time left on the ban and a
+// limit of zero.
+//
+// If the ban store fails, the error is logged and the request is decided by the rate limiter alone.
+func (p *PenaltyBox) Take(ctx context.Context, key string, cost int) (*RateLimitDecision, error) {
+	now := p.clock.Now()
+
+	ban, banned, err := p.store.GetBan(ctx, key, now)
+	if err != nil {
+		p.log(slog.LevelError, "failed to get ban", slog.String(loggingKeyKey, key), slog.String(loggingKeyError, err.Error()))
+	} else if banned {
+		return bannedDecision(ban, now), nil
+	}
+
+	decision, err := p.limiter.Take(ctx, key, cost)
+	if err != nil || decision.Allowed {
+		return decision, err
+	}
+
+	ban, banned, err = p.violate(ctx, key, now)
+	if err != nil {
+		p.log(slog.LevelError, "failed to record rate limit violation", slog.String(loggingKeyKey, key), slog.String(loggingKeyError, err.Error()))
+		return decision, nil
+	} else if banned {
+		return bannedDecision(ban, now), nil
+	}
+
+	return decision, nil
+}
+
+// violate records a violation by the key, returning the ban and true if the key has reached the threshold.
+func (p *PenaltyBox) violate(ctx context.Context, key string, now time.Time) (*Ban, bool, error) {
+	violations, err := p.store.AddViolation(ctx, key, now, p.window)
+	if err != nil {
+		return nil, false, err
+	} else if violations < p.threshold {
+		return nil, false, nil
+	}
+
+	strikes, err := p.store.AddStrike(ctx, key, now, p.strikeTTL)
+	if err != nil {
+		return nil, false, err
+	}
+
+	ban := &Ban{
+		Key:     key,
+		Until:   now.Add(p.banDuration(strikes)),
+		Strikes: strikes,
+	}
+	if err := p.store.SetBan(ctx, ban, now); err != nil {
+		return nil, false, err
+	}
+
+	p.log(slog.LevelWarn, "banned rate limit key",
+		slog.String(loggingKeyKey, key),
+		slog.Int(loggingKeyCount, strikes),
+		slog.Time(loggingKeyUntil, ban.Until),
+	)
+
+	return ban, true, nil
+}
+
+// banDuration returns the length of the ban for the given strike, doubling the base ban for each previous strike.
+func (p *PenaltyBox) banDuration(strikes int) time.Duration {
+	d := p.baseBan
+	for i := 1; i < strikes && d < p.maxBan; i++ {
+		d *= 2
+	}
+	return min(d, p.maxBan)
+}
+
+// Bans returns the bans in force.
+func (p *PenaltyBox) Bans(ctx context.Context) ([]*Ban, error) {
+	return p.store.ListBans(ctx, p.clock.Now())
+}
+
+// Unban lifts the ban on the key and forgets its previous violations, so its next ban starts again at the base length.
+func (p *PenaltyBox) Unban(ctx context.Context, key string) error {
+	return p.store.DeleteBan(ctx, key)
+}
+
+// AdminHandler returns a handler to manage the bans. A GET lists the bans in force as JSON, and a DELETE lifts the ban
+// on the key given by the "key" query parameter.
+//
+// The handler does no authorisation of its own, so it should only be exposed to operators, for example behind
+// InternalOnly.
+func (p *PenaltyBox) AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			bans, err := p.Bans(r.Context())
+			if err != nil {
+				p.log(slog.LevelError, "failed to list bans", slog.String(loggingKeyError, err.Error()))
+				InternalServerErrorHandler().ServeHTTP(w, r)
+				return
+			}
+			MustEncode(w, http.StatusOK, bans)
+		case http.MethodDelete:
+			key := r.URL.Query().Get(banKeyQueryParam)
+			if key == "" {
+				GenericErrorHandler(w, r, errMissingBanKey)
+				return
+			}
+
+			if err := p.Unban(r.Context(), key); err != nil {
+				p.log(slog.LevelError, "failed to lift ban", slog.String(loggingKeyKey, key), slog.String(loggingKeyError, err.Error()))
+				InternalServerErrorHandler().ServeHTTP(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			MethodNotAllowedHandler().ServeHTTP(w, r)
+		}
+	})
+}
+
+func (p *PenaltyBox) log(level slog.Level, msg string, args ...any) {
+	if p.l == nil {
+		return
+	}
+
+	p.l.Log(context.Background(), level, msg, args...) // nolint:sloglint // Handler around the messages passed in to prevent panics on nil logger
+}
+
+// bannedDecision denies a request from a banned key.
+func bannedDecision(ban *Ban, now time.Time) *RateLimitDecision {
+	return &RateLimitDecision{
+		Allowed:    false,
+		Reset:      ban.Until,
+		RetryAfter: ban.Until.Sub(now),
+	}
+}
@@ -0,0 +1,151 @@
+package uhttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jacobbrewer1/uhttp/uhttptest"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPenaltyBox_Take(t *testing.T) {
+	ctx := context.Background()
+	clock := uhttptest.NewFakeClock(time.Now())
+
+	pb := NewPenaltyBox(NewRateLimiter(1, 1, WithClock(clock)), NewMemoryBanStore(),
+		WithPenaltyBoxClock(clock),
+		WithBanThreshold(2, time.Minute),
+		WithBanDuration(time.Minute, 3*time.Minute),
+	)
+
+	require.True(t, pb.Allow("key"))
+
+	// The first violation is an ordinary denial.
+	decision, err := pb.Take(ctx, "key", 1)
+	require.NoError(t, err)
+	require.False(t, decision.Allowed)
+	require.Equal(t, time.Second, decision.RetryAfter)
+
+	// The second violation bans the key.
+	decision, err = pb.Take(ctx, "key", 1)
+	require.NoError(t, err)
+	require.False(t, decision.Allowed)
+	require.Equal(t, time.Minute, decision.RetryAfter)
+
+	// The key stays banned after its quota has refilled.
+	clock.Advance(30 * time.Second)
+	decision, err = pb.Take(ctx, "key", 1)
+	require.NoError(t, err)
+	require.False(t, decision.Allowed)
+	require.Equal(t, 30*time.Second, decision.RetryAfter)
+	require.True(t, pb.Allow("other"))
+
+	clock.Advance(30 * time.Second)
+	require.True(t, pb.Allow("key"))
+}
+
+func TestPenaltyBox_Escalation(t *testing.T) {
+	ctx := context.Background()
+	clock := uhttptest.NewFakeClock(time.Now())
+
+	// A limiter with no quota makes every request a violation.
+	pb := NewPenaltyBox(NewRateLimiter(0, 0, WithClock(clock)), NewMemoryBanStore(),
+		WithPenaltyBoxClock(clock),
+		WithBanThreshold(1, time.Minute),
+		WithBanDuration(time.Minute, 3*time.Minute),
+	)
+
+	for _, want := range []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute} {
+		decision, err := pb.Take(ctx, "key", 1)
+		require.NoError(t, err)
+		require.Equal(t, want, decision.RetryAfter)
+		clock.Advance(want)
+	}
+
+	// Lifting the ban forgives the previous strikes.
+	_, err := pb.Take(ctx, "key", 1)
+	require.NoError(t, err)
+	require.NoError(t, pb.Unban(ctx, "key"))
+
+	bans, err := pb.Bans(ctx)
+	require.NoError(t, err)
+	require.Empty(t, bans)
+
+	decision, err := pb.Take(ctx, "key", 1)
+	require.NoError(t, err)
+	require.Equal(t, time.Minute, decision.RetryAfter)
+}
+
+func TestPenaltyBox_AdminHandler(t *testing.T) {
+	ctx := context.Background()
+	clock := uhttptest.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	pb := NewPenaltyBox(NewRateLimiter(0, 0, WithClock(clock)), NewMemoryBanStore(),
+		WithPenaltyBoxClock(clock),
+		WithBanThreshold(1, time.Minute),
+	)
+	handler := pb.AdminHandler()
+
+	require.False(t, pb.Allow("b"))
+	require.False(t, pb.Allow("a"))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	got := make([]*Ban, 0)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	require.Equal(t, []*Ban{
+		{Key: "a", Until: clock.Now().Add(time.Minute), Strikes: 1},
+		{Key: "b", Until: clock.Now().Add(time.Minute), Strikes: 1},
+	}, got)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/?key=a", http.NoBody))
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	bans, err := pb.Bans(ctx)
+	require.NoError(t, err)
+	require.Len(t, bans, 1)
+	require.Equal(t, "b", bans[0].Key)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/", http.NoBody))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", http.NoBody))
+	require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestPenaltyBox_AdminHandler_StoreError(t *testing.T) {
+	store := NewMockBanStore(t)
+	store.On("ListBans", mock.Anything, mock.Anything).Return(nil, errors.New("store down"))
+	store.On("DeleteBan", mock.Anything, "a").Return(errors.New("store down"))
+
+	handler := NewPenaltyBox(NewRateLimiter(1, 1), store).AdminHandler()
+
+	for _, r := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/", http.NoBody),
+		httptest.NewRequest(http.MethodDelete, "/?key=a", http.NoBody),
+	} {
+		r.Header.Set(requestIDHeader, "123")
+		r = r.WithContext(RequestIDToContext(r.Context(), r))
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		require.Equal(t, "123", w.Header().Get(HeaderRequestID))
+
+		resp := new(HTTPError)
+		require.NoError(t, DecodeJSON(w.Result().Body, resp))
+		require.Equal(t, "internal server error", resp.Detail)
+		require.Equal(t, "123", resp.RequestId)
+	}
+}
@@ -0,0 +1,149 @@
+package uhttp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// headerForwardedFor is the header proxies append the client address to.
+	headerForwardedFor = "X-Forwarded-For"
+
+	// compositeKeySeparator separates the parts of a composite key. It cannot appear in an IP address, a hex encoded
+	// hash or a route template.
+	compositeKeySeparator = "|"
+)
+
+// ClientIPKey returns a key function that rate limits by the client IP address.
+//
+// X-Forwarded-For is only trusted when the request comes from one of the trusted proxies, in which case the header is
+// walked from the right and the first address that is not a trusted proxy is used. This prevents clients from choosing
+// their own key by setting the header themselves.
+func ClientIPKey(trustedProxies ...netip.Prefix) RateLimitKeyFunc {
+	isTrusted := func(addr netip.Addr) bool {
+		for _, prefix := range trustedProxies {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(r *http.Request) string {
+		remote, err := parseAddr(r.RemoteAddr)
+		if err != nil {
+			return r.RemoteAddr
+		}
+
+		if !isTrusted(remote) {
+			return remote.String()
+		}
+
+		forwarded := strings.Split(strings.Join(r.Header.Values(headerForwardedFor), ","), ",")
+		for i := len(forwarded) - 1; i >= 0; i-- {
+			addr, err := parseAddr(strings.TrimSpace(forwarded[i]))
+			if err != nil {
+				// The header is malformed from this point, so nothing further left can be trusted.
+				break
+			}
+
+			if !isTrusted(addr) {
+				return addr.String()
+			}
+		}
+
+		return remote.String()
+	}
+}
+
+// AuthSubjectKey returns a key function that rate limits by the Authorization header stored in the request context by
+// AuthHeaderToContext. The value is hashed so credentials are never used as keys directly.
+//
+// Requests without an Authorization header are rate limited by their client IP address, as returned by ClientIPKey
+// with the trusted proxies, so that unauthenticated clients do not share a single quota.
+func AuthSubjectKey(trustedProxies ...netip.Prefix) RateLimitKeyFunc {
+	clientIPKey := ClientIPKey(trustedProxies...)
+
+	return func(r *http.Request) string {
+		if auth := AuthHeaderFromContext(r.Context()); auth != "" {
+			return hashKey(auth)
+		}
+		return clientIPKey(r)
+	}
+}
+
+// APIKeyKey returns a key function that rate limits by the API key sent in the given header. The value is hashed so
+// credentials are never used as keys directly.
+//
+// Requests without an API key are rate limited by their client IP address, as returned by ClientIPKey with the trusted
+// proxies, so that clients without a key do not share a single quota.
+func APIKeyKey(header string, trustedProxies ...netip.Prefix) RateLimitKeyFunc {
+	clientIPKey := ClientIPKey(trustedProxies...)
+
+	return func(r *http.Request) string {
+		if apiKey := r.Header.Get(header); apiKey != "" {
+			return hashKey(apiKey)
+		}
+		return clientIPKey(r)
+	}
+}
+
+// RouteKey returns a key function that rate limits by the gorilla mux route template the request matched, such as
+// "/users/{id}". Requests that did not match a route return an empty key.
+func RouteKey() RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		route := mux.CurrentRoute(r)
+		if route == nil {
+			return ""
+		}
+
+		tmpl, err := route.GetPathTemplate()
+		if err != nil {
+			return ""
+		}
+
+		return tmpl
+	}
+}
+
+// CompositeKey returns a key function that joins the keys of the given key functions, for example to limit each user
+// on each route independently.
+func CompositeKey(keyFuncs ...RateLimitKeyFunc) RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		keys := make([]string, len(keyFuncs))
+		for i, keyFunc := range keyFuncs {
+			keys[i] = keyFunc(r)
+		}
+		return strings.Join(keys, compositeKeySeparator)
+	}
+}
+
+// hashKey returns the hex encoded SHA-256 hash of the value, or an empty string if the value is empty.
+func hashKey(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// parseAddr parses an IP address that may have a port.
+func parseAddr(addr string) (netip.Addr, error) {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+
+	parsed, err := netip.ParseAddr(addr)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	return parsed.Unmap(), nil
+}
@@ -0,0 +1,135 @@
+package uhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientIPKey(t *testing.T) {
+	trusted := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{
+			name:       "remote address",
+			remoteAddr: "192.0.2.1:1234",
+			want:       "192.0.2.1",
+		},
+		{
+			name:       "untrusted proxy is ignored",
+			remoteAddr: "192.0.2.1:1234",
+			forwarded:  []string{"198.51.100.1"},
+			want:       "192.0.2.1",
+		},
+		{
+			name:       "trusted proxy",
+			remoteAddr: "10.0.0.1:1234",
+			forwarded:  []string{"198.51.100.1"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "spoofed address is skipped",
+			remoteAddr: "10.0.0.1:1234",
+			forwarded:  []string{"203.0.113.1, 198.51.100.1, 10.0.0.2"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "multiple headers",
+			remoteAddr: "10.0.0.1:1234",
+			forwarded:  []string{"203.0.113.1", "198.51.100.1"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "only trusted proxies",
+			remoteAddr: "10.0.0.1:1234",
+			forwarded:  []string{"10.0.0.2"},
+			want:       "10.0.0.1",
+		},
+		{
+			name:       "malformed header",
+			remoteAddr: "10.0.0.1:1234",
+			forwarded:  []string{"198.51.100.1, nonsense"},
+			want:       "10.0.0.1",
+		},
+		{
+			name:       "ipv6",
+			remoteAddr: "[2001:db8::1]:1234",
+			want:       "2001:db8::1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			r.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.forwarded {
+				r.Header.Add(headerForwardedFor, v)
+			}
+
+			require.Equal(t, tt.want, ClientIPKey(trusted...)(r))
+		})
+	}
+}
+
+func TestAuthSubjectKey(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set(headerForwardedFor, "192.0.2.1")
+
+	// Unauthenticated requests are limited by client IP.
+	require.Equal(t, "10.0.0.1", AuthSubjectKey()(r))
+	require.Equal(t, "192.0.2.1", AuthSubjectKey(netip.MustParsePrefix("10.0.0.0/8"))(r))
+
+	r = r.WithContext(AuthToContext(r.Context(), "Bearer token"))
+	key := AuthSubjectKey()(r)
+	require.Len(t, key, 64)
+	require.NotContains(t, key, "token")
+}
+
+func TestAPIKeyKey(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set(headerForwardedFor, "192.0.2.1")
+
+	// Requests without a key are limited by client IP.
+	require.Equal(t, "10.0.0.1", APIKeyKey("X-API-Key")(r))
+	require.Equal(t, "192.0.2.1", APIKeyKey("X-API-Key", netip.MustParsePrefix("10.0.0.0/8"))(r))
+
+	r.Header.Set("X-API-Key", "secret")
+	key := APIKeyKey("X-API-Key")(r)
+	require.Len(t, key, 64)
+	require.NotContains(t, key, "secret")
+}
+
+func TestRouteKey(t *testing.T) {
+	require.Empty(t, RouteKey()(httptest.NewRequest(http.MethodGet, "/users/1", http.NoBody)))
+
+	var got string
+	router := mux.NewRouter()
+	router.HandleFunc("/users/{id}", func(_ http.ResponseWriter, r *http.Request) {
+		got = RouteKey()(r)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", http.NoBody))
+	require.Equal(t, "/users/{id}", got)
+}
+
+func TestCompositeKey(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	r.RemoteAddr = "192.0.2.1:1234"
+	r.Header.Set("X-API-Key", "secret")
+
+	key := CompositeKey(ClientIPKey(), APIKeyKey("X-API-Key"))(r)
+	require.Equal(t, "192.0.2.1|"+hashKey("secret"), key)
+}
@@ -0,0 +1,106 @@
+package uhttp
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimitKeyFunc returns the key that the request is rate limited by.
+type RateLimitKeyFunc func(r *http.Request) string
+
+// RateLimitCostFunc returns how many units of the quota the request consumes.
+type RateLimitCostFunc func(r *http.Request) int
+
+// RateLimitMiddlewareOption configures the rate limiting middlewares.
+type RateLimitMiddlewareOption func(*rateLimitMiddleware)
+
+type rateLimitMiddleware struct {
+	// costFunc returns the cost of the request. When nil every request costs one.
+	costFunc RateLimitCostFunc
+
+	// clock tells the time the RateLimit-Reset header counts down from.
+	clock Clock
+}
+
+// WithRateLimitCost sets the function used to weigh each request, so that expensive requests consume more of the
+// quota.
+func WithRateLimitCost(costFunc RateLimitCostFunc) RateLimitMiddlewareOption {
+	return func(m *rateLimitMiddleware) {
+		m.costFunc = costFunc
+	}
+}
+
+// WithRateLimitClock sets the clock the RateLimit-Reset header is computed with. It should be the clock the limiter
+// was created with. The default is the system clock.
+func WithRateLimitClock(clock Clock) RateLimitMiddlewareOption {
+	return func(m *rateLimitMiddleware) {
+		m.clock = clock
+	}
+}
+
+func newRateLimitMiddleware(opts ...RateLimitMiddlewareOption) *rateLimitMiddleware {
+	m := &rateLimitMiddleware{
+		clock: systemClock{},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// RateLimit returns a middleware that rate limits requests by the key returned from keyFunc.
+//
+// Every response carries the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers. Rejected requests are
+// answered with a 429 and a Retry-After header. If the limiter fails, the request is rejected with a 503, as the
+// client was not throttled.
+func RateLimit(limiter RateLimiter, keyFunc RateLimitKeyFunc, opts ...RateLimitMiddlewareOption) MiddlewareFunc {
+	m := newRateLimitMiddleware(opts...)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			m.rateLimit(next, w, r, limiter, keyFunc(r))
+		})
+	}
+}
+
+// rateLimit checks the limit for the key, passing the request to next if allowed and rejecting it otherwise.
+func (m *rateLimitMiddleware) rateLimit(next http.Handler, w http.ResponseWriter, r *http.Request, limiter RateLimiter, key string) {
+	cost := 1
+	if m.costFunc != nil {
+		cost = m.costFunc(r)
+	}
+
+	decision, err := limiter.Take(r.Context(), key, cost)
+	if err != nil {
+		slog.Error("Error checking rate limit", slog.String(loggingKeyError, err.Error()))
+		ServiceUnavailableHandler().ServeHTTP(w, r)
+		return
+	}
+
+	setRateLimitHeaders(w.Header(), decision, m.clock.Now())
+
+	if !decision.Allowed {
+		if decision.RetryAfter > 0 {
+			w.Header().Set(HeaderRetryAfter, formatDeltaSeconds(decision.RetryAfter))
+		}
+		TooManyRequestsHandler().ServeHTTP(w, r)
+		return
+	}
+
+	next.ServeHTTP(w, r)
+}
+
+// setRateLimitHeaders sets the IETF RateLimit headers describing the decision as of now.
+func setRateLimitHeaders(h http.Header, decision *RateLimitDecision, now time.Time) {
+	h.Set(HeaderRateLimitLimit, strconv.Itoa(decision.Limit))
+	h.Set(HeaderRateLimitRemaining, strconv.Itoa(decision.Remaining))
+	h.Set(HeaderRateLimitReset, formatDeltaSeconds(decision.Reset.Sub(now)))
+}
+
+// formatDeltaSeconds formats the duration as a whole number of seconds, rounding up so that clients never retry early.
+func formatDeltaSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(max(d, 0).Seconds())), 10)
+}
@@ -0,0 +1,165 @@
+package uhttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jacobbrewer1/uhttp/uhttptest"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimit(t *testing.T) {
+	handler := WrapHandler(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}, RateLimit(NewRateLimiter(1, 2), func(r *http.Request) string {
+		return r.Header.Get("X-Key")
+	}))
+
+	tests := []struct {
+		name          string
+		key           string
+		wantStatus    int
+		wantRemaining string
+		wantRetry     string
+	}{
+		{
+			name:          "first request",
+			key:           "a",
+			wantStatus:    http.StatusNoContent,
+			wantRemaining: "1",
+		},
+		{
+			name:          "second request",
+			key:           "a",
+			wantStatus:    http.StatusNoContent,
+			wantRemaining: "0",
+		},
+		{
+			name:          "limited",
+			key:           "a",
+			wantStatus:    http.StatusTooManyRequests,
+			wantRemaining: "0",
+			wantRetry:     "1",
+		},
+		{
+			name:          "other key",
+			key:           "b",
+			wantStatus:    http.StatusNoContent,
+			wantRemaining: "1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			r.Header.Set("X-Key", tt.key)
+			r.Header.Set(requestIDHeader, "123")
+			r = r.WithContext(RequestIDToContext(r.Context(), r))
+
+			handler.ServeHTTP(w, r)
+
+			require.Equal(t, tt.wantStatus, w.Code)
+			require.Equal(t, "2", w.Header().Get(HeaderRateLimitLimit))
+			require.Equal(t, tt.wantRemaining, w.Header().Get(HeaderRateLimitRemaining))
+			require.NotEmpty(t, w.Header().Get(HeaderRateLimitReset))
+			require.Equal(t, tt.wantRetry, w.Header().Get(HeaderRetryAfter))
+
+			if tt.wantStatus != http.StatusTooManyRequests {
+				return
+			}
+
+			resp := new(HTTPError)
+			require.NoError(t, DecodeJSON(w.Result().Body, resp))
+			require.Equal(t, http.StatusTooManyRequests, resp.Status)
+			require.Equal(t, "Too Many Requests", resp.Title)
+			require.Equal(t, "too many requests", resp.Detail)
+			require.Equal(t, "123", resp.RequestId)
+		})
+	}
+}
+
+func TestRateLimit_LimiterError(t *testing.T) {
+	limiter := NewMockRateLimiter(t)
+	limiter.On("Take", mock.Anything, "key", 1).Return(nil, errors.New("redis unavailable"))
+
+	handler := WrapHandler(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler should not be called")
+	}, RateLimit(limiter, func(*http.Request) string {
+		return "key"
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	require.Empty(t, w.Header().Get(HeaderRateLimitLimit))
+}
+
+func TestRateLimit_Clock(t *testing.T) {
+	clock := uhttptest.NewFakeClock(time.Unix(1_700_000_000, 0))
+	limiter := NewMockRateLimiter(t)
+	limiter.On("Take", mock.Anything, "key", 1).Return(&RateLimitDecision{
+		Allowed:   true,
+		Limit:     10,
+		Remaining: 9,
+		Reset:     clock.Now().Add(30 * time.Second),
+	}, nil)
+
+	handler := WrapHandler(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}, RateLimit(limiter, func(*http.Request) string {
+		return "key"
+	}, WithRateLimitClock(clock)))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+	require.Equal(t, "30", w.Header().Get(HeaderRateLimitReset))
+
+	clock.Advance(20 * time.Second)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+	require.Equal(t, "10", w.Header().Get(HeaderRateLimitReset))
+}
+
+func TestRateLimit_Cost(t *testing.T) {
+	handler := WrapHandler(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}, RateLimit(NewRateLimiter(1, 10), func(*http.Request) string {
+		return "key"
+	}, WithRateLimitCost(func(r *http.Request) int {
+		if r.URL.Path == "/export" {
+			return 5
+		}
+		return 1
+	})))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, http.NoBody))
+		return w
+	}
+
+	w := serve("/export")
+	require.Equal(t, http.StatusNoContent, w.Code)
+	require.Equal(t, "5", w.Header().Get(HeaderRateLimitRemaining))
+
+	w = serve("/search")
+	require.Equal(t, http.StatusNoContent, w.Code)
+	require.Equal(t, "4", w.Header().Get(HeaderRateLimitRemaining))
+
+	// The export costs more than the remaining quota, but a cheap request still fits.
+	w = serve("/export")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.Equal(t, "4", w.Header().Get(HeaderRateLimitRemaining))
+	require.NotEmpty(t, w.Header().Get(HeaderRetryAfter))
+
+	w = serve("/search")
+	require.Equal(t, http.StatusNoContent, w.Code)
+	require.Equal(t, "3", w.Header().Get(HeaderRateLimitRemaining))
+}
@@ -0,0 +1,209 @@
+package uhttp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/jacobbrewer1/goredis"
+	"gopkg.in/yaml.v3"
+)
+
+// RateLimitPolicy describes a limit and the requests it applies to.
+type RateLimitPolicy struct {
+	// Name identifies the policy. Keys are namespaced by the name so policies never share a quota.
+	Name string `json:"name" yaml:"name"`
+
+	// Routes are the route patterns the policy applies to, in path.Match syntax. They are matched against the gorilla
+	// mux route template when the request matched a route, and the URL path otherwise. Empty matches every route.
+	Routes []string `json:"routes" yaml:"routes"`
+
+	// Methods are the HTTP methods the policy applies to. Empty matches every method.
+	Methods []string `json:"methods" yaml:"methods"`
+
+	// Tiers are the tiers the policy applies to, as returned by the RateLimitTierFunc. Empty matches every tier.
+	Tiers []string `json:"tiers" yaml:"tiers"`
+
+	// RPS is the requests per second.
+	RPS float64 `json:"rps" yaml:"rps"`
+
+	// Burst is the burst.
+	Burst int `json:"burst" yaml:"burst"`
+}
+
+// RateLimitPolicyConfig is the declarative configuration of the rate limit policies.
+type RateLimitPolicyConfig struct {
+	// Policies are checked in order, and the first policy that matches a request is applied.
+	Policies []RateLimitPolicy `json:"policies" yaml:"policies"`
+}
+
+// RateLimitTierFunc returns the tier of the request, such as "free" or "paid".
+type RateLimitTierFunc func(r *http.Request) string
+
+// RateLimiterFactory creates the rate limiter for a policy.
+type RateLimiterFactory func(policy RateLimitPolicy) RateLimiter
+
+// RedisRateLimiterConstructor creates a Redis backed rate limiter, such as NewRedisGCRARateLimiter.
+type RedisRateLimiterConstructor = func(keydb goredis.Pool, rps float64, burst int, opts ...RateLimiterOption) RateLimiter
+
+// ParseRateLimitPolicyConfig parses the rate limit policy configuration from YAML or JSON.
+func ParseRateLimitPolicyConfig(data []byte) (*RateLimitPolicyConfig, error) {
+	cfg := new(RateLimitPolicyConfig)
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parse rate limit policy config: %w", err)
+	}
+	return cfg, nil
+}
+
+// LoadRateLimitPolicyConfig reads the rate limit policy configuration from a YAML or JSON file.
+func LoadRateLimitPolicyConfig(filename string) (*RateLimitPolicyConfig, error) {
+	data, err := os.ReadFile(filename) // nolint:gosec // The file is chosen by the service, not the client
+	if err != nil {
+		return nil, fmt.Errorf("read rate limit policy config: %w", err)
+	}
+	return ParseRateLimitPolicyConfig(data)
+}
+
+// InMemoryRateLimiterFactory returns a factory that creates an in-memory rate limiter for each policy, named after
+// the policy.
+func InMemoryRateLimiterFactory(opts ...RateLimiterOption) RateLimiterFactory {
+	return func(policy RateLimitPolicy) RateLimiter {
+		return NewRateLimiter(policy.RPS, policy.Burst, append([]RateLimiterOption{WithName(policy.Name)}, opts...)...)
+	}
+}
+
+// RedisRateLimiterFactory returns a factory that creates a Redis backed rate limiter for each policy using the given
+// constructor, named after the policy.
+func RedisRateLimiterFactory(keydb goredis.Pool, newLimiter RedisRateLimiterConstructor, opts ...RateLimiterOption) RateLimiterFactory {
+	return func(policy RateLimitPolicy) RateLimiter {
+		return newLimiter(keydb, policy.RPS, policy.Burst, append([]RateLimiterOption{WithName(policy.Name)}, opts...)...)
+	}
+}
+
+// RateLimitPolicies resolves the policy and limiter that apply to a request.
+type RateLimitPolicies struct {
+	policies []*RateLimitPolicy
+	limiters []RateLimiter
+	tierFunc RateLimitTierFunc
+}
+
+// NewRateLimitPolicies validates the configuration and builds a limiter for each policy. The tier function may be nil
+// if no policy is restricted by tier.
+func NewRateLimitPolicies(cfg *RateLimitPolicyConfig, factory RateLimiterFactory, tierFunc RateLimitTierFunc) (*RateLimitPolicies, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid rate limit policy config: %w", err)
+	}
+
+	p := &RateLimitPolicies{
+		policies: make([]*RateLimitPolicy, len(cfg.Policies)),
+		limiters: make([]RateLimiter, len(cfg.Policies)),
+		tierFunc: tierFunc,
+	}
+
+	for i := range cfg.Policies {
+		p.policies[i] = &cfg.Policies[i]
+		p.limiters[i] = factory(cfg.Policies[i])
+	}
+
+	return p, nil
+}
+
+// Resolve returns the first policy that matches the request, and its limiter. It returns false if no policy matches.
+func (p *RateLimitPolicies) Resolve(r *http.Request) (*RateLimitPolicy, RateLimiter, bool) {
+	route := r.URL.Path
+	if tmpl := RouteKey()(r); tmpl != "" {
+		route = tmpl
+	}
+
+	tier := ""
+	if p.tierFunc != nil {
+		tier = p.tierFunc(r)
+	}
+
+	for i, policy := range p.policies {
+		if policy.matches(route, r.Method, tier) {
+			return policy, p.limiters[i], true
+		}
+	}
+
+	return nil, nil, false
+}
+
+// RateLimitByPolicy returns a middleware that rate limits each request by the policy that matches it. The key is
+// namespaced by the policy name. Requests that match no policy are not limited.
+func RateLimitByPolicy(policies *RateLimitPolicies, keyFunc RateLimitKeyFunc, opts ...RateLimitMiddlewareOption) MiddlewareFunc {
+	m := newRateLimitMiddleware(opts...)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			policy, limiter, ok := policies.Resolve(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			m.rateLimit(next, w, r, limiter, policy.Name+compositeKeySeparator+keyFunc(r))
+		})
+	}
+}
+
+func (p *RateLimitPolicy) matches(route, method, tier string) bool {
+	if len(p.Methods) > 0 && !slices.ContainsFunc(p.Methods, func(m string) bool {
+		return strings.EqualFold(m, method)
+	}) {
+		return false
+	}
+
+	if len(p.Tiers) > 0 && !slices.Contains(p.Tiers, tier) {
+		return false
+	}
+
+	if len(p.Routes) == 0 {
+		return true
+	}
+
+	for _, pattern := range p.Routes {
+		if matched, _ := path.Match(pattern, route); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *RateLimitPolicyConfig) validate() error {
+	if len(c.Policies) == 0 {
+		return errors.New("no policies")
+	}
+
+	names := make(map[string]struct{}, len(c.Policies))
+	for i := range c.Policies {
+		policy := &c.Policies[i]
+
+		switch {
+		case policy.Name == "":
+			return fmt.Errorf("policy %d has no name", i)
+		case policy.RPS < 0:
+			return fmt.Errorf("policy %q has a negative rps", policy.Name)
+		case policy.Burst < 0:
+			return fmt.Errorf("policy %q has a negative burst", policy.Name)
+		}
+
+		if _, ok := names[policy.Name]; ok {
+			return fmt.Errorf("policy %q is defined more than once", policy.Name)
+		}
+		names[policy.Name] = struct{}{}
+
+		for _, pattern := range policy.Routes {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("policy %q has an invalid route pattern %q: %w", policy.Name, pattern, err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,206 @@
+package uhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+const testRateLimitPolicyConfig = `
+policies:
+  - name: export-free
+    routes: ["/exports/*"]
+    methods: [POST]
+    tiers: [free]
+    rps: 1
+    burst: 1
+  - name: export
+    routes: ["/exports/*"]
+    methods: [POST]
+    rps: 10
+    burst: 20
+  - name: default
+    rps: 100
+    burst: 100
+`
+
+func TestParseRateLimitPolicyConfig(t *testing.T) {
+	t.Run("yaml", func(t *testing.T) {
+		cfg, err := ParseRateLimitPolicyConfig([]byte(testRateLimitPolicyConfig))
+		require.NoError(t, err)
+		require.Len(t, cfg.Policies, 3)
+		require.Equal(t, RateLimitPolicy{
+			Name:    "export-free",
+			Routes:  []string{"/exports/*"},
+			Methods: []string{http.MethodPost},
+			Tiers:   []string{"free"},
+			RPS:     1,
+			Burst:   1,
+		}, cfg.Policies[0])
+	})
+
+	t.Run("json", func(t *testing.T) {
+		cfg, err := ParseRateLimitPolicyConfig([]byte(`{"policies":[{"name":"default","rps":1.5,"burst":3}]}`))
+		require.NoError(t, err)
+		require.Equal(t, []RateLimitPolicy{{Name: "default", RPS: 1.5, Burst: 3}}, cfg.Policies)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := ParseRateLimitPolicyConfig([]byte(`policies: {`))
+		require.Error(t, err)
+	})
+}
+
+func TestNewRateLimitPolicies_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     RateLimitPolicyConfig
+		wantErr string
+	}{
+		{
+			name:    "no policies",
+			cfg:     RateLimitPolicyConfig{},
+			wantErr: "invalid rate limit policy config: no policies",
+		},
+		{
+			name:    "no name",
+			cfg:     RateLimitPolicyConfig{Policies: []RateLimitPolicy{{RPS: 1}}},
+			wantErr: "invalid rate limit policy config: policy 0 has no name",
+		},
+		{
+			name:    "duplicate name",
+			cfg:     RateLimitPolicyConfig{Policies: []RateLimitPolicy{{Name: "a"}, {Name: "a"}}},
+			wantErr: `invalid rate limit policy config: policy "a" is defined more than once`,
+		},
+		{
+			name:    "negative rps",
+			cfg:     RateLimitPolicyConfig{Policies: []RateLimitPolicy{{Name: "a", RPS: -1}}},
+			wantErr: `invalid rate limit policy config: policy "a" has a negative rps`,
+		},
+		{
+			name:    "bad route pattern",
+			cfg:     RateLimitPolicyConfig{Policies: []RateLimitPolicy{{Name: "a", Routes: []string{"/["}}}},
+			wantErr: `invalid rate limit policy config: policy "a" has an invalid route pattern "/[": syntax error in pattern`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRateLimitPolicies(&tt.cfg, InMemoryRateLimiterFactory(), nil)
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestRateLimitPolicies_Resolve(t *testing.T) {
+	cfg, err := ParseRateLimitPolicyConfig([]byte(testRateLimitPolicyConfig))
+	require.NoError(t, err)
+
+	policies, err := NewRateLimitPolicies(cfg, InMemoryRateLimiterFactory(), func(r *http.Request) string {
+		return r.Header.Get("X-Tier")
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		tier   string
+		want   string
+	}{
+		{
+			name:   "free tier export",
+			method: http.MethodPost,
+			path:   "/exports/1",
+			tier:   "free",
+			want:   "export-free",
+		},
+		{
+			name:   "paid tier export",
+			method: http.MethodPost,
+			path:   "/exports/1",
+			tier:   "paid",
+			want:   "export",
+		},
+		{
+			name:   "method is case insensitive",
+			method: "post",
+			path:   "/exports/1",
+			want:   "export",
+		},
+		{
+			name:   "other method",
+			method: http.MethodGet,
+			path:   "/exports/1",
+			tier:   "free",
+			want:   "default",
+		},
+		{
+			name:   "other route",
+			method: http.MethodPost,
+			path:   "/users",
+			tier:   "free",
+			want:   "default",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, http.NoBody)
+			r.Header.Set("X-Tier", tt.tier)
+
+			policy, limiter, ok := policies.Resolve(r)
+			require.True(t, ok)
+			require.NotNil(t, limiter)
+			require.Equal(t, tt.want, policy.Name)
+		})
+	}
+}
+
+func TestRateLimitByPolicy(t *testing.T) {
+	cfg, err := ParseRateLimitPolicyConfig([]byte(`
+policies:
+  - name: users
+    routes: ["/users/{id}"]
+    rps: 1
+    burst: 1
+`))
+	require.NoError(t, err)
+
+	policies, err := NewRateLimitPolicies(cfg, InMemoryRateLimiterFactory(), nil)
+	require.NoError(t, err)
+
+	router := mux.NewRouter()
+	router.Use(RateLimitByPolicy(policies, func(*http.Request) string {
+		return "key"
+	}))
+	router.HandleFunc("/users/{id}", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	router.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, http.NoBody))
+		return w
+	}
+
+	require.Equal(t, http.StatusNoContent, serve("/users/1").Code)
+
+	// The policy matches the route template, so every user shares the quota.
+	w := serve("/users/2")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.Equal(t, "1", w.Header().Get(HeaderRateLimitLimit))
+
+	// Requests that match no policy are not limited.
+	for range 3 {
+		w = serve("/health")
+		require.Equal(t, http.StatusNoContent, w.Code)
+		require.Empty(t, w.Header().Get(HeaderRateLimitLimit))
+	}
+}
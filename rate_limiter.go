@@ -3,7 +3,8 @@ package uhttp
 import (
 	"context"
 	"log/slog"
-	"sync"
+	"math"
+	"time"
 
 	"golang.org/x/time/rate"
 )
@@ -11,14 +12,74 @@ import (
 type RateLimiter interface {
 	// Allow returns true if the request is allowed.
 	Allow(key string) bool
+
+	// AllowN returns true if a request costing n units of the quota is allowed.
+	AllowN(key string, n int) bool
+
+	// Take consumes cost units of the quota for the key and returns the decision. A cost of less than one is treated
+	// as one. The quota is only consumed when the request is allowed.
+	Take(ctx context.Context, key string, cost int) (*RateLimitDecision, error)
+}
+
+// KeyCounter is implemented by rate limiters that hold state for each key in memory.
+type KeyCounter interface {
+	// Len returns the number of keys currently held.
+	Len() int
+}
+
+// RateLimitDecision is the outcome of a rate limit check.
+type RateLimitDecision struct {
+	// Allowed is true if the request is allowed.
+	Allowed bool
+
+	// Limit is the maximum quota available to a key.
+	Limit int
+
+	// Remaining is the quota left after this request.
+	Remaining int
+
+	// Reset is the time at which the quota will be fully restored.
+	Reset time.Time
+
+	// RetryAfter is how long to wait before the request would be allowed. It is zero when the request is allowed, or
+	// when the cost exceeds the limit and so the request can never be allowed.
+	RetryAfter time.Duration
 }
 
 type rateLimiter struct {
+	limiterConfig
+
 	ctx context.Context
-	l   *slog.Logger
 
-	// limiter is the limiter.
-	limiters sync.Map
+	// name identifies the rate limiter, usually by its policy, in the metrics.
+	name string
+
+	// metrics records the decisions made. It is nil when metrics are disabled.
+	metrics *rateLimiterMetrics
+
+	// limiters holds the limiter for each key.
+	limiters *limiterStore
+
+	// idleTTL is how long a key can go unused before it is evicted. Zero disables the sweeper.
+	idleTTL time.Duration
+
+	// maxKeys is the maximum number of keys to hold before evicting the least recently used. Zero means unbounded.
+	maxKeys int
+
+	// failureMode decides how the Redis rate limiters answer when Redis is unavailable.
+	failureMode RateLimiterFailureMode
+
+	// fallbackScale scales the limits of the in-memory fallback used by RateLimiterFailLocal.
+	fallbackScale float64
+
+	// breakerThreshold is the number of consecutive Redis failures that open the circuit breaker. Zero disables it.
+	breakerThreshold int
+
+	// breakerCooldown is how long the circuit breaker stays open before Redis is tried again.
+	breakerCooldown time.Duration
+
+	// window is the window of the fixed and sliding window Redis rate limiters.
+	window time.Duration
 
 	// rps is the requests per second.
 	rps float64
@@ -34,7 +95,11 @@ func NewRateLimiter(rps float64, burst int, opts ...RateLimiterOption) RateLimit
 	}
 
 	rl := &rateLimiter{
+		limiterConfig: limiterConfig{
+			clock: systemClock{},
+		},
 		ctx:   context.Background(),
+		name:  defaultRateLimiterName,
 		rps:   rps,
 		burst: burst,
 	}
@@ -43,27 +108,149 @@ func NewRateLimiter(rps float64, burst int, opts ...RateLimiterOption) RateLimit
 		opt(rl)
 	}
 
+	rl.init()
+
 	return rl
 }
 
+// init creates the store for the per-key limiters and starts the idle sweeper. It must be called once the options
+// have been applied.
+func (r *rateLimiter) init() {
+	r.limiters = newLimiterStore(r.maxKeys, func() *rate.Limiter {
+		return rate.NewLimiter(rate.Limit(r.rps), r.burst)
+	})
+
+	if r.idleTTL > 0 {
+		go r.sweep()
+	}
+}
+
 // Allow returns true if the request is allowed.
 func (r *rateLimiter) Allow(key string) bool {
-	// Rate limits the request.
-	gotLimiter, _ := r.limiters.LoadOrStore(key, rate.NewLimiter(rate.Limit(r.rps), r.burst))
+	return r.AllowN(key, 1)
+}
+
+// AllowN returns true if a request costing n units of the quota is allowed.
+func (r *rateLimiter) AllowN(key string, n int) bool {
+	decision, err := r.Take(context.Background(), key, n)
+	return r.allowed(key, decision, err)
+}
+
+// Take consumes cost units of the quota for the key and returns the decision.
+func (r *rateLimiter) Take(_ context.Context, key string, cost int) (*RateLimitDecision, error) {
+	decision := r.take(key, cost)
+
+	if r.metrics != nil {
+		r.metrics.observeDecision(r.name, decision, nil)
+		r.metrics.setKeys(r.name, r.limiters.len())
+	}
 
-	limiter, ok := gotLimiter.(*rate.Limiter)
-	if !ok {
-		r.log(slog.LevelError, "failed to cast rate limiter", slog.String(loggingKeyKey, key))
+	return decision, nil
+}
+
+func (r *rateLimiter) take(key string, cost int) *RateLimitDecision {
+	cost = max(cost, 1)
+
+	now := r.clock.Now()
+	limiter := r.limiters.get(key, now)
+
+	decision := &RateLimitDecision{
+		Allowed:   limiter.AllowN(now, cost),
+		Limit:     r.burst,
+		Remaining: r.burst,
+		Reset:     now,
+	}
+
+	if math.IsInf(r.rps, 1) {
+		return decision
+	}
+
+	tokens := limiter.TokensAt(now)
+	decision.Remaining = max(int(math.Floor(tokens)), 0)
+	decision.Reset = now.Add(r.refillDuration(float64(r.burst) - tokens))
+	if !decision.Allowed && cost <= r.burst {
+		decision.RetryAfter = r.refillDuration(float64(cost) - tokens)
+	}
+
+	return decision
+}
+
+// Len returns the number of keys currently held in memory.
+func (r *rateLimiter) Len() int {
+	if r.limiters == nil {
+		return 0
+	}
+	return r.limiters.len()
+}
+
+// sweep periodically evicts the keys that have been idle for longer than the idle TTL, until the context is done.
+func (r *rateLimiter) sweep() {
+	ticker := time.NewTicker(max(r.idleTTL/2, time.Millisecond))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+			if evicted := r.limiters.evictIdle(r.clock.Now().Add(-r.idleTTL)); evicted > 0 {
+				r.log(slog.LevelDebug, "evicted idle rate limiter keys", slog.Int(loggingKeyCount, evicted))
+				r.metrics.setKeys(r.name, r.limiters.len())
+			}
+		}
+	}
+}
+
+// refillDuration returns how long it takes to refill the given number of tokens.
+func (r *rateLimiter) refillDuration(tokens float64) time.Duration {
+	if tokens <= 0 || r.rps <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(tokens / r.rps * float64(time.Second)))
+}
+
+// allowed adapts the result of Take to the boolean Allow API, rejecting the request on error.
+func (r *rateLimiter) allowed(key string, decision *RateLimitDecision, err error) bool {
+	if err != nil {
+		r.log(slog.LevelError, "failed to check rate limit", slog.String(loggingKeyKey, key), slog.String(loggingKeyError, err.Error()))
 		return false
 	}
+	return decision.Allowed
+}
+
+// limiterConfig is the configuration shared by the rate limiters and the other limiters that keep their state in
+// Redis.
+type limiterConfig struct {
+	l *slog.Logger
 
-	return limiter.Allow()
+	// clock tells the time used to refill the buckets, evict idle keys and expire leases.
+	clock Clock
+
+	// keyPrefix is the prefix applied to every key written to Redis.
+	keyPrefix string
+
+	// hashTag wraps the key in a Redis Cluster hash tag so that every Redis key for it lands on the same slot.
+	hashTag bool
+}
+
+// redisKey returns the Redis key for the rate limit key, namespaced by the key prefix and the kind of limiter. When
+// hash tags are enabled the rate limit key is wrapped in braces, so any suffix appended to the Redis key stays on the
+// same Redis Cluster slot.
+func (c *limiterConfig) redisKey(kind, key string) string {
+	if c.hashTag {
+		key = "{" + key + "}"
+	}
+
+	if kind == "" {
+		return c.keyPrefix + key
+	}
+	return c.keyPrefix + kind + ":" + key
 }
 
-func (r *rateLimiter) log(level slog.Level, msg string, args ...any) {
-	if r.l == nil {
+func (c *limiterConfig) log(level slog.Level, msg string, args ...any) {
+	if c.l == nil {
 		return
 	}
 
-	r.l.Log(context.Background(), level, msg, args...) // nolint:sloglint // Handler around the messages passed in to prevent panics on nil logger
+	c.l.Log(context.Background(), level, msg, args...) // nolint:sloglint // Handler around the messages passed in to prevent panics on nil logger
 }
@@ -0,0 +1,121 @@
+package uhttp
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// defaultRateLimiterName is the policy label used for rate limiters that have not been named.
+	defaultRateLimiterName = "default"
+
+	// fallbackRateLimiterSuffix is appended to the policy label of the in-memory limiter used by RateLimiterFailLocal.
+	fallbackRateLimiterSuffix = "_fallback"
+
+	rateLimitResultAllowed = "allowed"
+	rateLimitResultDenied  = "denied"
+	rateLimitResultError   = "error"
+)
+
+// rateLimiterMetrics are the Prometheus metrics recorded by the rate limiters. A nil rateLimiterMetrics records
+// nothing.
+type rateLimiterMetrics struct {
+	// decisions counts the decisions made, by policy and result.
+	decisions *prometheus.CounterVec
+
+	// redisDuration observes the duration of the Redis round trips, by policy.
+	redisDuration *prometheus.HistogramVec
+
+	// redisFailures counts the checks Redis did not answer, by policy.
+	redisFailures *prometheus.CounterVec
+
+	// keys is the number of keys held in memory, by policy.
+	keys *prometheus.GaugeVec
+}
+
+// newRateLimiterMetrics registers the rate limiter metrics with the registerer. Metrics that are already registered,
+// for example by another rate limiter, are reused. It panics if the metrics cannot be registered.
+func newRateLimiterMetrics(reg prometheus.Registerer) *rateLimiterMetrics {
+	return &rateLimiterMetrics{
+		decisions: mustRegisterOrExisting(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "rate_limiter_decisions_total",
+			Help: "Number of rate limit decisions, by policy and result",
+		}, []string{"policy", "result"})),
+		redisDuration: mustRegisterOrExisting(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "rate_limiter_redis_duration_seconds",
+			Help:    "Duration of the Redis round trips made by the rate limiters",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"policy"})),
+		redisFailures: mustRegisterOrExisting(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "rate_limiter_redis_failures_total",
+			Help: "Number of rate limit checks Redis did not answer, including those stopped by the circuit breaker",
+		}, []string{"policy"})),
+		keys: mustRegisterOrExisting(reg, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rate_limiter_keys",
+			Help: "Number of keys held in memory by the rate limiters",
+		}, []string{"policy"})),
+	}
+}
+
+// observeDecision counts the outcome of a rate limit check.
+func (m *rateLimiterMetrics) observeDecision(policy string, decision *RateLimitDecision, err error) {
+	if m == nil {
+		return
+	}
+
+	result := rateLimitResultAllowed
+	switch {
+	case err != nil:
+		result = rateLimitResultError
+	case !decision.Allowed:
+		result = rateLimitResultDenied
+	}
+
+	m.decisions.WithLabelValues(policy, result).Inc()
+}
+
+// observeRedis records the duration of a Redis round trip.
+func (m *rateLimiterMetrics) observeRedis(policy string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.redisDuration.WithLabelValues(policy).Observe(duration.Seconds())
+}
+
+// observeRedisFailure counts a check that Redis did not answer, which is then answered by the failure mode.
+func (m *rateLimiterMetrics) observeRedisFailure(policy string) {
+	if m == nil {
+		return
+	}
+
+	m.redisFailures.WithLabelValues(policy).Inc()
+}
+
+// setKeys records the number of keys held in memory.
+func (m *rateLimiterMetrics) setKeys(policy string, count int) {
+	if m == nil {
+		return
+	}
+
+	m.keys.WithLabelValues(policy).Set(float64(count))
+}
+
+// mustRegisterOrExisting registers the collector, returning the collector that is already registered if there is one.
+func mustRegisterOrExisting[T prometheus.Collector](reg prometheus.Registerer, collector T) T {
+	err := reg.Register(collector)
+	if err == nil {
+		return collector
+	}
+
+	var alreadyRegistered prometheus.AlreadyRegisteredError
+	if errors.As(err, &alreadyRegistered) {
+		if existing, ok := alreadyRegistered.ExistingCollector.(T); ok {
+			return existing
+		}
+	}
+
+	panic(err)
+}
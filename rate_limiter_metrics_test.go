@@ -0,0 +1,105 @@
+package uhttp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jacobbrewer1/goredis"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithMetrics(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+
+	free := NewRateLimiter(1, 1, WithName("free"), WithMetrics(reg))
+	paid := NewRateLimiter(1, 2, WithName("paid"), WithMetrics(reg))
+
+	require.True(t, free.Allow("a"))
+	require.False(t, free.Allow("a"))
+	require.True(t, free.Allow("b"))
+	require.True(t, paid.Allow("a"))
+
+	err := testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP rate_limiter_decisions_total Number of rate limit decisions, by policy and result
+# TYPE rate_limiter_decisions_total counter
+rate_limiter_decisions_total{policy="free",result="allowed"} 2
+rate_limiter_decisions_total{policy="free",result="denied"} 1
+rate_limiter_decisions_total{policy="paid",result="allowed"} 1
+# HELP rate_limiter_keys Number of keys held in memory by the rate limiters
+# TYPE rate_limiter_keys gauge
+rate_limiter_keys{policy="free"} 2
+rate_limiter_keys{policy="paid"} 1
+`), "rate_limiter_decisions_total", "rate_limiter_keys")
+	require.NoError(t, err)
+}
+
+func TestWithMetrics_Redis(t *testing.T) {
+	tests := []struct {
+		name        string
+		failureMode RateLimiterFailureMode
+		want        string
+	}{
+		{
+			name:        "fail closed",
+			failureMode: RateLimiterFailClosed,
+			want: `
+rate_limiter_decisions_total{policy="default",result="error"} 1
+`,
+		},
+		{
+			name:        "fail open",
+			failureMode: RateLimiterFailOpen,
+			want: `
+rate_limiter_decisions_total{policy="default",result="allowed"} 1
+`,
+		},
+		{
+			name:        "fail local",
+			failureMode: RateLimiterFailLocal,
+			want: `
+rate_limiter_decisions_total{policy="default_fallback",result="allowed"} 1
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := prometheus.NewPedanticRegistry()
+
+			pool := goredis.NewMockPool(t)
+			pool.On("Conn").Return(unavailableConn{})
+
+			rl := NewRedisGCRARateLimiter(pool, 1, 1, WithMetrics(reg), WithFailureMode(tt.failureMode))
+
+			_, _ = rl.Take(context.Background(), "key", 1)
+
+			// The Redis failure is counted whatever the failure mode answered.
+			err := testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP rate_limiter_decisions_total Number of rate limit decisions, by policy and result
+# TYPE rate_limiter_decisions_total counter`+tt.want+`# HELP rate_limiter_redis_failures_total Number of rate limit checks Redis did not answer, including those stopped by the circuit breaker
+# TYPE rate_limiter_redis_failures_total counter
+rate_limiter_redis_failures_total{policy="default"} 1
+`), "rate_limiter_decisions_total", "rate_limiter_redis_failures_total")
+			require.NoError(t, err)
+
+			count, err := testutil.GatherAndCount(reg, "rate_limiter_redis_duration_seconds")
+			require.NoError(t, err)
+			require.Equal(t, 1, count)
+		})
+	}
+}
+
+func TestWithMetrics_RegistrationConflict(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "rate_limiter_decisions_total",
+		Help: "Conflicting metric",
+	}))
+
+	require.Panics(t, func() {
+		NewRateLimiter(1, 1, WithMetrics(reg))
+	})
+}
@@ -3,6 +3,9 @@ package uhttp
 import (
 	"context"
 	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 type RateLimiterOption = func(*rateLimiter)
@@ -20,3 +23,100 @@ func WithLogger(l *slog.Logger) RateLimiterOption {
 		r.l = l
 	}
 }
+
+// WithClock sets the clock the rate limiter tells the time with. The default is the system clock.
+//
+// The Redis limiters pass the time from the clock to Redis, except for the fixed window limiter whose window is timed
+// by the key expiry in Redis.
+func WithClock(clock Clock) RateLimiterOption {
+	return func(r *rateLimiter) {
+		r.clock = clock
+	}
+}
+
+// WithIdleTTL evicts the in-memory state for keys that have not been used for the given duration. The sweeper runs
+// until the context set by WithContext is done.
+//
+// A key that is evicted before its bucket has refilled starts again with a full bucket, so the TTL should be at least
+// burst / rps.
+func WithIdleTTL(ttl time.Duration) RateLimiterOption {
+	return func(r *rateLimiter) {
+		r.idleTTL = ttl
+	}
+}
+
+// WithMaxKeys caps the number of keys held in memory, evicting the least recently used key when the cap is reached.
+func WithMaxKeys(maxKeys int) RateLimiterOption {
+	return func(r *rateLimiter) {
+		r.maxKeys = maxKeys
+	}
+}
+
+// WithFailureMode sets how the Redis rate limiters answer when Redis is unavailable. The default is
+// RateLimiterFailClosed.
+func WithFailureMode(mode RateLimiterFailureMode) RateLimiterOption {
+	return func(r *rateLimiter) {
+		r.failureMode = mode
+	}
+}
+
+// WithLocalFallbackScale scales the rps and burst of the in-memory limiter used by RateLimiterFailLocal. When the
+// quota is shared by N instances, a scale of 1/N keeps the total limit roughly the same while Redis is unavailable.
+func WithLocalFallbackScale(scale float64) RateLimiterOption {
+	return func(r *rateLimiter) {
+		r.fallbackScale = scale
+	}
+}
+
+// WithCircuitBreaker stops the Redis rate limiters from calling Redis for the cooldown after threshold consecutive
+// failures, answering with the failure mode instead. After the cooldown a single request is let through to check
+// whether Redis has recovered.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) RateLimiterOption {
+	return func(r *rateLimiter) {
+		r.breakerThreshold = threshold
+		r.breakerCooldown = cooldown
+	}
+}
+
+// WithName sets the name of the rate limiter, used as the policy label of its metrics. Raw keys are never used as
+// labels, as their cardinality is unbounded.
+func WithName(name string) RateLimiterOption {
+	return func(r *rateLimiter) {
+		r.name = name
+	}
+}
+
+// WithMetrics records the rate limiter decisions, Redis round trip durations, Redis failures and in-memory key count in
+// Prometheus metrics registered with the registerer. Rate limiters sharing a registerer share the metrics, distinguished
+// by their name. The in-memory limiter used by RateLimiterFailLocal records its decisions under the name with a
+// "_fallback" suffix. It panics if the metrics cannot be registered.
+func WithMetrics(reg prometheus.Registerer) RateLimiterOption {
+	return func(r *rateLimiter) {
+		r.metrics = newRateLimiterMetrics(reg)
+	}
+}
+
+// WithKeyPrefix sets the prefix applied to every key the Redis rate limiters write, so that services sharing a Redis
+// server do not share quotas. The default is "rate_limit:".
+func WithKeyPrefix(prefix string) RateLimiterOption {
+	return func(r *rateLimiter) {
+		r.keyPrefix = prefix
+	}
+}
+
+// WithHashTag wraps the rate limit key in a Redis Cluster hash tag, for example "rate_limit:sliding_counter:{key}:1",
+// so that the Redis keys used for a rate limit key all hash to the same slot. It is required on Redis Cluster for the
+// sliding window counter rate limiter, whose script reads two keys.
+func WithHashTag() RateLimiterOption {
+	return func(r *rateLimiter) {
+		r.hashTag = true
+	}
+}
+
+// WithWindow sets the window of the fixed and sliding window Redis rate limiters, which allow burst requests in each
+// window. The default is one second. The GCRA and in-memory rate limiters refill continuously and ignore it.
+func WithWindow(window time.Duration) RateLimiterOption {
+	return func(r *rateLimiter) {
+		r.window = window
+	}
+}
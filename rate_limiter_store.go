@@ -0,0 +1,98 @@
+package uhttp
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// limiterStore holds the in-memory limiter for each key. Keys are kept in least recently used order so that idle keys
+// can be swept and, when a maximum number of keys is set, the least recently used key can be evicted to make room.
+type limiterStore struct {
+	mu sync.Mutex
+
+	// entries maps each key to its element in lru.
+	entries map[string]*list.Element
+
+	// lru orders the entries from the most recently used at the front to the least recently used at the back.
+	lru *list.List
+
+	// maxKeys is the maximum number of keys to hold. Zero means unbounded.
+	maxKeys int
+
+	// newLimiter creates the limiter for a new key.
+	newLimiter func() *rate.Limiter
+}
+
+type limiterEntry struct {
+	key      string
+	limiter  *rate.Limiter
+	lastUsed time.Time
+}
+
+func newLimiterStore(maxKeys int, newLimiter func() *rate.Limiter) *limiterStore {
+	return &limiterStore{
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+		maxKeys:    maxKeys,
+		newLimiter: newLimiter,
+	}
+}
+
+// get returns the limiter for the key, creating it if needed, and marks it as used at the given time.
+func (s *limiterStore) get(key string, now time.Time) *rate.Limiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.entries[key]; ok {
+		entry := elem.Value.(*limiterEntry) // nolint:forcetypeassert // Only limiterEntry values are stored
+		entry.lastUsed = now
+		s.lru.MoveToFront(elem)
+		return entry.limiter
+	}
+
+	if s.maxKeys > 0 && s.lru.Len() >= s.maxKeys {
+		s.remove(s.lru.Back())
+	}
+
+	entry := &limiterEntry{
+		key:      key,
+		limiter:  s.newLimiter(),
+		lastUsed: now,
+	}
+	s.entries[key] = s.lru.PushFront(entry)
+
+	return entry.limiter
+}
+
+// evictIdle removes every key that has not been used since the given time, returning the number removed.
+func (s *limiterStore) evictIdle(before time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	evicted := 0
+	for elem := s.lru.Back(); elem != nil; elem = s.lru.Back() {
+		if !elem.Value.(*limiterEntry).lastUsed.Before(before) { // nolint:forcetypeassert // Only limiterEntry values are stored
+			break
+		}
+		s.remove(elem)
+		evicted++
+	}
+
+	return evicted
+}
+
+// len returns the number of keys held.
+func (s *limiterStore) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lru.Len()
+}
+
+func (s *limiterStore) remove(elem *list.Element) {
+	entry := s.lru.Remove(elem).(*limiterEntry) // nolint:forcetypeassert // Only limiterEntry values are stored
+	delete(s.entries, entry.key)
+}
@@ -0,0 +1,181 @@
+package uhttp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jacobbrewer1/uhttp/uhttptest"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	rl := NewRateLimiter(1, 2)
+
+	require.True(t, rl.Allow("key"))
+	require.True(t, rl.Allow("key"))
+	require.False(t, rl.Allow("key"))
+
+	// Keys are limited independently.
+	require.True(t, rl.Allow("other"))
+}
+
+func TestRateLimiter_AllowN(t *testing.T) {
+	rl := NewRateLimiter(1, 5)
+
+	require.True(t, rl.AllowN("key", 3))
+	require.False(t, rl.AllowN("key", 3))
+	require.True(t, rl.AllowN("key", 2))
+	require.False(t, rl.Allow("key"))
+}
+
+func TestRateLimiter_Take(t *testing.T) {
+	tests := []struct {
+		name          string
+		costs         []int
+		wantAllowed   bool
+		wantRemaining int
+		wantRetry     bool
+	}{
+		{
+			name:          "allowed",
+			costs:         []int{1},
+			wantAllowed:   true,
+			wantRemaining: 4,
+		},
+		{
+			name:          "zero cost is treated as one",
+			costs:         []int{0},
+			wantAllowed:   true,
+			wantRemaining: 4,
+		},
+		{
+			name:          "cost consumes multiple tokens",
+			costs:         []int{3},
+			wantAllowed:   true,
+			wantRemaining: 2,
+		},
+		{
+			name:          "exhausted",
+			costs:         []int{5, 1},
+			wantAllowed:   false,
+			wantRemaining: 0,
+			wantRetry:     true,
+		},
+		{
+			name:          "denied requests do not consume quota",
+			costs:         []int{4, 2},
+			wantAllowed:   false,
+			wantRemaining: 1,
+			wantRetry:     true,
+		},
+		{
+			name:          "cost above the limit is never allowed",
+			costs:         []int{6},
+			wantAllowed:   false,
+			wantRemaining: 5,
+			wantRetry:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := uhttptest.NewFakeClock(time.Now())
+			rl := NewRateLimiter(1, 5, WithClock(clock))
+
+			var (
+				decision *RateLimitDecision
+				err      error
+			)
+			for _, cost := range tt.costs {
+				decision, err = rl.Take(context.Background(), "key", cost)
+				require.NoError(t, err)
+			}
+
+			require.Equal(t, tt.wantAllowed, decision.Allowed)
+			require.Equal(t, 5, decision.Limit)
+			require.Equal(t, tt.wantRemaining, decision.Remaining)
+			require.Equal(t, tt.wantRetry, decision.RetryAfter > 0)
+			require.Equal(t, clock.Now().Add(time.Duration(5-tt.wantRemaining)*time.Second), decision.Reset)
+		})
+	}
+}
+
+func TestRateLimiter_Refill(t *testing.T) {
+	clock := uhttptest.NewFakeClock(time.Now())
+	rl := NewRateLimiter(2, 2, WithClock(clock))
+
+	require.True(t, rl.AllowN("key", 2))
+
+	decision, err := rl.Take(context.Background(), "key", 1)
+	require.NoError(t, err)
+	require.False(t, decision.Allowed)
+	require.Equal(t, 500*time.Millisecond, decision.RetryAfter)
+
+	clock.Advance(499 * time.Millisecond)
+	require.False(t, rl.Allow("key"))
+
+	clock.Advance(time.Millisecond)
+	require.True(t, rl.Allow("key"))
+	require.False(t, rl.Allow("key"))
+
+	// A full bucket refills after burst / rps.
+	clock.Advance(time.Second)
+	require.True(t, rl.AllowN("key", 2))
+}
+
+func TestRateLimiter_MaxKeys(t *testing.T) {
+	rl := NewRateLimiter(1, 1, WithMaxKeys(2))
+
+	require.True(t, rl.Allow("a"))
+	require.True(t, rl.Allow("b"))
+	require.False(t, rl.Allow("a"))
+	require.Equal(t, 2, rl.(KeyCounter).Len())
+
+	// Adding a third key evicts the least recently used key, which is "b" as "a" was used last.
+	require.True(t, rl.Allow("c"))
+	require.Equal(t, 2, rl.(KeyCounter).Len())
+	require.False(t, rl.Allow("a"))
+	require.True(t, rl.Allow("b"))
+}
+
+func TestRateLimiter_IdleTTL(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	clock := uhttptest.NewFakeClock(time.Now())
+	rl := NewRateLimiter(1, 1, WithContext(ctx), WithClock(clock), WithIdleTTL(10*time.Millisecond))
+
+	require.True(t, rl.Allow("a"))
+	require.Equal(t, 1, rl.(KeyCounter).Len())
+
+	// The key is only idle once the clock has moved past the TTL.
+	clock.Advance(5 * time.Millisecond)
+	require.Never(t, func() bool {
+		return rl.(KeyCounter).Len() == 0
+	}, 30*time.Millisecond, 5*time.Millisecond)
+
+	clock.Advance(10 * time.Millisecond)
+
+	require.Eventually(t, func() bool {
+		return rl.(KeyCounter).Len() == 0
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestLimiterStore_EvictIdle(t *testing.T) {
+	now := time.Now()
+	store := newLimiterStore(0, func() *rate.Limiter {
+		return rate.NewLimiter(1, 1)
+	})
+
+	store.get("a", now)
+	store.get("b", now.Add(time.Second))
+	store.get("c", now.Add(2*time.Second))
+	store.get("a", now.Add(3*time.Second))
+
+	require.Equal(t, 1, store.evictIdle(now.Add(2*time.Second)))
+	require.Equal(t, 2, store.len())
+	require.Equal(t, 2, store.evictIdle(now.Add(4*time.Second)))
+	require.Equal(t, 0, store.len())
+}
@@ -0,0 +1,236 @@
+package uhttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/jacobbrewer1/goredis"
+)
+
+const (
+	redisBanKind       = "ban"
+	redisViolationKind = "ban_violations"
+	redisStrikeKind    = "ban_strikes"
+
+	// redisBanScanCount is the number of keys asked for in each SCAN when listing the bans.
+	redisBanScanCount = 100
+)
+
+// incrementExpiringScript increments a counter, setting its expiry on the first increment or on every increment when
+// asked to.
+//
+// KEYS[1] - the counter.
+// ARGV[1] - the TTL in milliseconds.
+// ARGV[2] - 1 to push back the expiry on every increment.
+var incrementExpiringScript = redis.NewScript(1, `
+local count = redis.call('INCR', KEYS[1])
+if count == 1 or ARGV[2] == '1' then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return count
+`)
+
+// setBanScript stores the ban and clears the violations of the key.
+//
+// KEYS[1] - the ban.
+// KEYS[2] - the violations counter.
+// ARGV[1] - the ban encoded as JSON.
+// ARGV[2] - the time until the ban expires, in milliseconds.
+var setBanScript = redis.NewScript(2, `
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+redis.call('DEL', KEYS[2])
+return 1
+`)
+
+// redisBanStore is a BanStore shared by every instance using the same Redis server.
+type redisBanStore struct {
+	keydb goredis.Pool
+
+	limiterConfig
+}
+
+// RedisBanStoreOption configures the Redis ban store.
+type RedisBanStoreOption func(*redisBanStore)
+
+// WithBanStoreLogger sets the logger for the Redis ban store.
+func WithBanStoreLogger(l *slog.Logger) RedisBanStoreOption {
+	return func(s *redisBanStore) {
+		s.l = l
+	}
+}
+
+// WithBanStoreKeyPrefix sets the prefix applied to every key the Redis ban store writes, so that services sharing a
+// Redis server do not share bans. The default is "rate_limit:".
+func WithBanStoreKeyPrefix(prefix string) RedisBanStoreOption {
+	return func(s *redisBanStore) {
+		s.keyPrefix = prefix
+	}
+}
+
+// NewRedisBanStore creates a new BanStore that holds the bans in Redis, so that they apply across every instance.
+//
+// The ban, violations and strikes of a key are held at "<prefix>ban:{<key>}", "<prefix>ban_violations:{<key>}" and
+// "<prefix>ban_strikes:{<key>}". The key is always wrapped in a hash tag so that they land on the same Redis Cluster
+// slot.
+func NewRedisBanStore(keydb goredis.Pool, opts ...RedisBanStoreOption) BanStore {
+	s := &redisBanStore{
+		limiterConfig: limiterConfig{
+			keyPrefix: redisRateLimitKeyPrefix,
+		},
+		keydb: keydb,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	// The scripts touch more than one key, so the keys must share a hash tag to run on Redis Cluster.
+	s.hashTag = true
+
+	return s
+}
+
+// AddViolation records a violation of the rate limit by the key.
+func (s *redisBanStore) AddViolation(ctx context.Context, key string, _ time.Time, window time.Duration) (int, error) {
+	count, err := redis.Int(s.runScript(ctx, incrementExpiringScript, s.redisKey(redisViolationKind, key), window.Milliseconds(), 0))
+	if err != nil {
+		return 0, fmt.Errorf("add violation: %w", err)
+	}
+	return count, nil
+}
+
+// AddStrike records that the key is being banned.
+func (s *redisBanStore) AddStrike(ctx context.Context, key string, _ time.Time, ttl time.Duration) (int, error) {
+	count, err := redis.Int(s.runScript(ctx, incrementExpiringScript, s.redisKey(redisStrikeKind, key), ttl.Milliseconds(), 1))
+	if err != nil {
+		return 0, fmt.Errorf("add strike: %w", err)
+	}
+	return count, nil
+}
+
+// SetBan bans the key until the ban expires.
+func (s *redisBanStore) SetBan(ctx context.Context, ban *Ban, now time.Time) error {
+	ttl := ban.Until.Sub(now).Milliseconds()
+	if ttl <= 0 {
+		return nil
+	}
+
+	value, err := json.Marshal(ban)
+	if err != nil {
+		return fmt.Errorf("encode ban: %w", err)
+	}
+
+	if _, err := s.runScript(ctx, setBanScript, s.redisKey(redisBanKind, ban.Key), s.redisKey(redisViolationKind, ban.Key), value, ttl); err != nil {
+		return fmt.Errorf("set ban: %w", err)
+	}
+	return nil
+}
+
+// GetBan returns the ban on the key.
+func (s *redisBanStore) GetBan(ctx context.Context, key string, now time.Time) (*Ban, bool, error) {
+	return s.getBan(ctx, s.redisKey(redisBanKind, key), now)
+}
+
+// getBan reads the ban stored at the Redis key.
+func (s *redisBanStore) getBan(ctx context.Context, redisKey string, now time.Time) (*Ban, bool, error) {
+	value, err := redis.Bytes(s.keydb.DoCtx(ctx, "GET", redisKey))
+	if errors.Is(err, redis.ErrNil) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, fmt.Errorf("get ban: %w", err)
+	}
+
+	ban := new(Ban)
+	if err := json.Unmarshal(value, ban); err != nil {
+		return nil, false, fmt.Errorf("decode ban: %w", err)
+	} else if !now.Before(ban.Until) {
+		return nil, false, nil
+	}
+
+	return ban, true, nil
+}
+
+// ListBans returns the bans in force, ordered by key. On Redis Cluster only the bans held by the node the connection is
+// made to are listed.
+func (s *redisBanStore) ListBans(ctx context.Context, now time.Time) ([]*Ban, error) {
+	keys, err := s.scanBanKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	bans := make([]*Ban, 0, len(keys))
+	for _, key := range keys {
+		ban, ok, err := s.getBan(ctx, key, now)
+		if err != nil {
+			return nil, err
+		} else if ok {
+			bans = append(bans, ban)
+		}
+	}
+
+	slices.SortFunc(bans, func(a, b *Ban) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	return bans, nil
+}
+
+// DeleteBan lifts the ban on the key.
+func (s *redisBanStore) DeleteBan(ctx context.Context, key string) error {
+	if _, err := s.keydb.DoCtx(ctx, "DEL",
+		s.redisKey(redisBanKind, key),
+		s.redisKey(redisViolationKind, key),
+		s.redisKey(redisStrikeKind, key),
+	); err != nil {
+		return fmt.Errorf("delete ban: %w", err)
+	}
+	return nil
+}
+
+// scanBanKeys returns the Redis keys of every ban.
+func (s *redisBanStore) scanBanKeys(ctx context.Context) ([]string, error) {
+	conn := s.keydb.Conn()
+	defer func() {
+		if err := conn.Close(); err != nil {
+			s.log(slog.LevelWarn, "Error closing redis connection", slog.String(loggingKeyError, err.Error()))
+		}
+	}()
+
+	keys := make([]string, 0)
+	cursor := 0
+	for {
+		reply, err := redis.Values(redis.DoContext(conn, ctx, "SCAN", cursor, "MATCH", s.keyPrefix+redisBanKind+":*", "COUNT", redisBanScanCount))
+		if err != nil {
+			return nil, fmt.Errorf("scan bans: %w", err)
+		}
+
+		page := make([]string, 0)
+		if _, err := redis.Scan(reply, &cursor, &page); err != nil {
+			return nil, fmt.Errorf("scan bans: %w", err)
+		}
+		keys = append(keys, page...)
+
+		if cursor == 0 {
+			return keys, nil
+		}
+	}
+}
+
+// runScript executes the Lua script atomically on the Redis server.
+func (s *redisBanStore) runScript(ctx context.Context, script *redis.Script, keysAndArgs ...any) (any, error) {
+	conn := s.keydb.Conn()
+	defer func() {
+		if err := conn.Close(); err != nil {
+			s.log(slog.LevelWarn, "Error closing redis connection", slog.String(loggingKeyError, err.Error()))
+		}
+	}()
+
+	return script.DoContext(ctx, conn, keysAndArgs...)
+}
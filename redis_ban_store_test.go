@@ -0,0 +1,123 @@
+package uhttp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jacobbrewer1/uhttp/uhttptest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisBanStore(t *testing.T) {
+	pool, m := newMiniredisPool(t)
+	ctx := context.Background()
+	now := time.Unix(1_700_000_000, 0).UTC()
+	store := NewRedisBanStore(pool)
+
+	// Violations count within the window, which starts at the first one.
+	for want := 1; want <= 3; want++ {
+		count, err := store.AddViolation(ctx, "key", now, time.Minute)
+		require.NoError(t, err)
+		require.Equal(t, want, count)
+	}
+	require.Equal(t, time.Minute, m.TTL("rate_limit:ban_violations:{key}"))
+
+	m.FastForward(time.Minute)
+	count, err := store.AddViolation(ctx, "key", now, time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	// Every strike pushes back the expiry of the strikes.
+	count, err = store.AddStrike(ctx, "key", now, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	m.FastForward(30 * time.Minute)
+	count, err = store.AddStrike(ctx, "key", now, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+	require.Equal(t, time.Hour, m.TTL("rate_limit:ban_strikes:{key}"))
+
+	// Setting the ban clears the violations.
+	ban := &Ban{Key: "key", Until: now.Add(time.Minute), Strikes: 2}
+	require.NoError(t, store.SetBan(ctx, ban, now))
+	require.False(t, m.Exists("rate_limit:ban_violations:{key}"))
+	require.Equal(t, time.Minute, m.TTL("rate_limit:ban:{key}"))
+
+	got, ok, err := store.GetBan(ctx, "key", now)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, ban, got)
+
+	// A ban that has expired by the clock is not returned, even before Redis expires it.
+	_, ok, err = store.GetBan(ctx, "key", ban.Until)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// A ban that has already expired is not stored.
+	require.NoError(t, store.SetBan(ctx, &Ban{Key: "expired", Until: now}, now))
+	require.False(t, m.Exists("rate_limit:ban:{expired}"))
+
+	require.NoError(t, store.SetBan(ctx, &Ban{Key: "another", Until: now.Add(time.Hour), Strikes: 1}, now))
+
+	bans, err := store.ListBans(ctx, now)
+	require.NoError(t, err)
+	require.Equal(t, []*Ban{
+		{Key: "another", Until: now.Add(time.Hour), Strikes: 1},
+		ban,
+	}, bans)
+
+	// Lifting the ban forgets the strikes too.
+	require.NoError(t, store.DeleteBan(ctx, "key"))
+	require.Equal(t, []string{"rate_limit:ban:{another}"}, m.Keys())
+}
+
+func TestRedisBanStore_KeyPrefix(t *testing.T) {
+	pool, m := newMiniredisPool(t)
+	ctx := context.Background()
+	now := time.Unix(1_700_000_000, 0).UTC()
+	store := NewRedisBanStore(pool, WithBanStoreKeyPrefix("svc:"))
+
+	_, err := store.AddViolation(ctx, "key", now, time.Minute)
+	require.NoError(t, err)
+	_, err = store.AddStrike(ctx, "key", now, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, store.SetBan(ctx, &Ban{Key: "key", Until: now.Add(time.Minute)}, now))
+
+	// A ban held under another prefix is not listed.
+	require.NoError(t, NewRedisBanStore(pool).SetBan(ctx, &Ban{Key: "other", Until: now.Add(time.Minute)}, now))
+
+	require.Equal(t, []string{"rate_limit:ban:{other}", "svc:ban:{key}", "svc:ban_strikes:{key}"}, m.Keys())
+
+	bans, err := store.ListBans(ctx, now)
+	require.NoError(t, err)
+	require.Len(t, bans, 1)
+	require.Equal(t, "key", bans[0].Key)
+}
+
+func TestPenaltyBox_RedisBanStore(t *testing.T) {
+	pool, m := newMiniredisPool(t)
+	ctx := context.Background()
+	clock := uhttptest.NewFakeClock(time.Unix(1_700_000_000, 0))
+
+	// A limiter with no quota makes every request a violation.
+	pb := NewPenaltyBox(NewRateLimiter(0, 0, WithClock(clock)), NewRedisBanStore(pool),
+		WithPenaltyBoxClock(clock),
+		WithBanThreshold(2, time.Minute),
+		WithBanDuration(time.Minute, 3*time.Minute),
+	)
+
+	for _, want := range []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute} {
+		decision, err := pb.Take(ctx, "key", 1)
+		require.NoError(t, err)
+		require.Less(t, decision.RetryAfter, time.Minute)
+
+		decision, err = pb.Take(ctx, "key", 1)
+		require.NoError(t, err)
+		require.Equal(t, want, decision.RetryAfter)
+
+		clock.Advance(want)
+		m.FastForward(want)
+	}
+}
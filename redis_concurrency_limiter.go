@@ -0,0 +1,158 @@
+package uhttp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/google/uuid"
+	"github.com/jacobbrewer1/goredis"
+)
+
+// defaultConcurrencyLeaseTTL is the lease TTL used when the one given is under a millisecond, the precision of the
+// lease expiry in Redis.
+const defaultConcurrencyLeaseTTL = time.Minute
+
+// concurrencyAcquireScript takes a lease in a sorted set scored by the lease expiry, after dropping the leases that
+// have expired without being released.
+//
+// KEYS[1] - the sorted set holding the leases.
+// ARGV[1] - the current time in microseconds.
+// ARGV[2] - the lease TTL in microseconds.
+// ARGV[3] - the maximum number of leases.
+// ARGV[4] - the lease ID.
+var concurrencyAcquireScript = redis.NewScript(1, `
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local ttl = tonumber(ARGV[2])
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now)
+if redis.call('ZCARD', key) >= tonumber(ARGV[3]) then
+	return 0
+end
+
+redis.call('ZADD', key, now + ttl, ARGV[4])
+redis.call('PEXPIRE', key, math.ceil(ttl / 1000))
+return 1
+`)
+
+// redisConcurrencyLimiter is a concurrency limiter that holds a lease in Redis for each request in flight, so the
+// limit is shared across every instance using the same Redis server.
+type redisConcurrencyLimiter struct {
+	keydb goredis.Pool
+
+	// limit is the maximum number of requests in flight for a key.
+	limit int
+
+	// leaseTTL is how long a lease is held before it expires, in case the instance holding it dies.
+	leaseTTL time.Duration
+
+	limiterConfig
+}
+
+// RedisConcurrencyLimiterOption configures the Redis concurrency limiter.
+type RedisConcurrencyLimiterOption func(*redisConcurrencyLimiter)
+
+// WithConcurrencyLimiterClock sets the clock the leases are timed with. The default is the system clock.
+func WithConcurrencyLimiterClock(clock Clock) RedisConcurrencyLimiterOption {
+	return func(c *redisConcurrencyLimiter) {
+		c.clock = clock
+	}
+}
+
+// WithConcurrencyLimiterLogger sets the logger for the Redis concurrency limiter.
+func WithConcurrencyLimiterLogger(l *slog.Logger) RedisConcurrencyLimiterOption {
+	return func(c *redisConcurrencyLimiter) {
+		c.l = l
+	}
+}
+
+// WithConcurrencyLimiterKeyPrefix sets the prefix applied to the keys of the leases, so that services sharing a Redis
+// server do not share limits. The default is "rate_limit:".
+func WithConcurrencyLimiterKeyPrefix(prefix string) RedisConcurrencyLimiterOption {
+	return func(c *redisConcurrencyLimiter) {
+		c.keyPrefix = prefix
+	}
+}
+
+// WithConcurrencyLimiterHashTag wraps the key in a Redis Cluster hash tag, for example "rate_limit:concurrency:{key}",
+// so that the leases of a key land on the same slot as its rate limits.
+func WithConcurrencyLimiterHashTag() RedisConcurrencyLimiterOption {
+	return func(c *redisConcurrencyLimiter) {
+		c.hashTag = true
+	}
+}
+
+// NewRedisConcurrencyLimiter creates a new concurrency limiter that allows up to limit requests in flight for each key
+// across every instance sharing the Redis server.
+//
+// Leases that are not released, for example because the instance died, expire after the lease TTL. The TTL should be
+// longer than the slowest request, otherwise the lease of a request still in flight can expire and its slot be reused.
+// A TTL under a millisecond, which Redis would expire at once, is replaced by a TTL of one minute.
+//
+// Leases are held at "<prefix>concurrency:<key>".
+func NewRedisConcurrencyLimiter(keydb goredis.Pool, limit int, leaseTTL time.Duration, opts ...RedisConcurrencyLimiterOption) ConcurrencyLimiter {
+	if leaseTTL < time.Millisecond {
+		leaseTTL = defaultConcurrencyLeaseTTL
+	}
+
+	c := &redisConcurrencyLimiter{
+		limiterConfig: limiterConfig{
+			clock:     systemClock{},
+			keyPrefix: redisRateLimitKeyPrefix,
+		},
+		keydb:    keydb,
+		limit:    limit,
+		leaseTTL: leaseTTL,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Acquire reserves a slot for the key.
+func (c *redisConcurrencyLimiter) Acquire(ctx context.Context, key string) (release func(), ok bool, err error) {
+	redisKey := c.redisKey("concurrency", key)
+	lease := uuid.NewString()
+
+	conn := c.keydb.Conn()
+	defer func() {
+		if closeErr := conn.Close(); closeErr != nil {
+			c.log(slog.LevelWarn, "Error closing redis connection", slog.String(loggingKeyError, closeErr.Error()))
+		}
+	}()
+
+	reply, err := redis.Int64(concurrencyAcquireScript.DoContext(ctx, conn,
+		redisKey,
+		c.clock.Now().UnixMicro(),
+		c.leaseTTL.Microseconds(),
+		c.limit,
+		lease,
+	))
+	if err != nil {
+		return nil, false, fmt.Errorf("acquire concurrency lease: %w", err)
+	} else if reply != 1 {
+		return nil, false, nil
+	}
+
+	// The lease must be released even if the request was cancelled.
+	releaseCtx := context.WithoutCancel(ctx)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if _, err := c.keydb.DoCtx(releaseCtx, "ZREM", redisKey, lease); err != nil {
+				c.log(slog.LevelWarn, "Error releasing concurrency lease",
+					slog.String(loggingKeyKey, redisKey),
+					slog.String(loggingKeyError, err.Error()),
+				)
+			}
+		})
+	}, true, nil
+}
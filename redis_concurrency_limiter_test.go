@@ -0,0 +1,85 @@
+package uhttp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jacobbrewer1/uhttp/uhttptest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisConcurrencyLimiter(t *testing.T) {
+	pool, m := newMiniredisPool(t)
+	clock := uhttptest.NewFakeClock(time.Unix(1_700_000_000, 0))
+	cl := NewRedisConcurrencyLimiter(pool, 2, time.Minute, WithConcurrencyLimiterClock(clock))
+	ctx := context.Background()
+
+	releaseA, ok, err := cl.Acquire(ctx, "key")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, ok, err = cl.Acquire(ctx, "key")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, ok, err = cl.Acquire(ctx, "key")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// Releasing more than once only frees one slot.
+	releaseA()
+	releaseA()
+
+	_, ok, err = cl.Acquire(ctx, "key")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, ok, err = cl.Acquire(ctx, "key")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// Leases that are never released expire after the TTL.
+	clock.Advance(time.Minute)
+
+	_, ok, err = cl.Acquire(ctx, "key")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.Equal(t, []string{"rate_limit:concurrency:key"}, m.Keys())
+	require.Equal(t, time.Minute, m.TTL("rate_limit:concurrency:key"))
+}
+
+func TestRedisConcurrencyLimiter_Keys(t *testing.T) {
+	pool, m := newMiniredisPool(t)
+	cl := NewRedisConcurrencyLimiter(pool, 1, time.Minute, WithConcurrencyLimiterKeyPrefix("svc:"), WithConcurrencyLimiterHashTag())
+
+	release, ok, err := cl.Acquire(context.Background(), "key")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.Equal(t, []string{"svc:concurrency:{key}"}, m.Keys())
+
+	release()
+	require.Empty(t, m.Keys())
+}
+
+func TestRedisConcurrencyLimiter_LeaseTTL(t *testing.T) {
+	for _, leaseTTL := range []time.Duration{0, time.Microsecond} {
+		t.Run(leaseTTL.String(), func(t *testing.T) {
+			pool, m := newMiniredisPool(t)
+			cl := NewRedisConcurrencyLimiter(pool, 1, leaseTTL)
+
+			_, ok, err := cl.Acquire(context.Background(), "key")
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			// The lease is held for the default TTL rather than expiring at once.
+			require.Equal(t, defaultConcurrencyLeaseTTL, m.TTL("rate_limit:concurrency:key"))
+
+			_, ok, err = cl.Acquire(context.Background(), "key")
+			require.NoError(t, err)
+			require.False(t, ok)
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package uhttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/jacobbrewer1/goredis"
+)
+
+const (
+	defaultRedisEventKeyPrefix = "sse:"
+
+	redisEventFieldEvent = "event"
+	redisEventFieldData  = "data"
+	redisEventFieldRetry = "retry"
+
+	// redisEventFollowCount is the most events read from a stream at once when following it.
+	redisEventFollowCount = 100
+
+	// redisStreamFirstID is the ID before every entry of a stream.
+	redisStreamFirstID = "0-0"
+)
+
+// redisEventStore is an EventStore that holds the events of each topic in a Redis stream, shared by every instance.
+type redisEventStore struct {
+	keydb goredis.Pool
+
+	// maxLen is the approximate number of events kept in each stream.
+	maxLen int
+
+	// keyPrefix is prepended to the topic to give the key of its stream.
+	keyPrefix string
+}
+
+// RedisEventStoreOption configures the Redis event store.
+type RedisEventStoreOption func(*redisEventStore)
+
+// WithEventStoreKeyPrefix sets the prefix applied to the key of the stream of each topic, so that services sharing a
+// Redis server do not share events. The default is "sse:".
+func WithEventStoreKeyPrefix(prefix string) RedisEventStoreOption {
+	return func(s *redisEventStore) {
+		s.keyPrefix = prefix
+	}
+}
+
+// NewRedisEventStore creates a new EventStore that holds roughly the last maxLen events of each topic in a Redis
+// stream, at "<prefix><topic>". Event IDs are the IDs of the stream entries. Replay needs Redis 6.2 or later.
+//
+// The store is an EventFollower, so a broker using it delivers the events published on every instance to its
+// subscribers. Each topic with subscribers holds one connection from the pool, blocked waiting for events.
+func NewRedisEventStore(keydb goredis.Pool, maxLen int, opts ...RedisEventStoreOption) EventStore {
+	s := &redisEventStore{
+		keydb:     keydb,
+		maxLen:    maxLen,
+		keyPrefix: defaultRedisEventKeyPrefix,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// key returns the key of the stream of the topic.
+func (s *redisEventStore) key(topic string) string {
+	return s.keyPrefix + topic
+}
+
+// Append adds the event to the stream of the topic, trimming the stream to about maxLen events.
+func (s *redisEventStore) Append(ctx context.Context, topic string, ev *Event) error {
+	args := []any{
+		s.key(topic), "MAXLEN", "~", s.maxLen, "*",
+		redisEventFieldEvent, ev.Event,
+		redisEventFieldData, ev.Data,
+	}
+	if ev.Retry > 0 {
+		args = append(args, redisEventFieldRetry, ev.Retry.Milliseconds())
+	}
+
+	id, err := redis.String(s.keydb.DoCtx(ctx, "XADD", args...))
+	if err != nil {
+		return fmt.Errorf("append event: %w", err)
+	}
+
+	ev.ID = id
+	return nil
+}
+
+// Since returns the events in the stream of the topic after the entry with the ID.
+func (s *redisEventStore) Since(ctx context.Context, topic, lastID string) ([]*Event, error) {
+	if !validStreamID(lastID) {
+		return make([]*Event, 0), nil
+	}
+
+	reply, err := redis.Values(s.keydb.DoCtx(ctx, "XRANGE", s.key(topic), "("+lastID, "+", "COUNT", s.maxLen))
+	if err != nil {
+		return nil, fmt.Errorf("replay events: %w", err)
+	}
+
+	return parseStreamEntries(reply)
+}
+
+// LastID returns the ID of the last entry in the stream of the topic, or "0-0" if the stream is empty.
+func (s *redisEventStore) LastID(ctx context.Context, topic string) (string, error) {
+	reply, err := redis.Values(s.keydb.DoCtx(ctx, "XREVRANGE", s.key(topic), "+", "-", "COUNT", 1))
+	if err != nil {
+		return "", fmt.Errorf("last event id: %w", err)
+	}
+
+	events, err := parseStreamEntries(reply)
+	if err != nil {
+		return "", err
+	} else if len(events) == 0 {
+		return redisStreamFirstID, nil
+	}
+
+	return events[0].ID, nil
+}
+
+// Follow blocks until entries are added to the stream of the topic after the entry with the ID, or the timeout passes.
+func (s *redisEventStore) Follow(ctx context.Context, topic, lastID string, timeout time.Duration) ([]*Event, error) {
+	reply, err := redis.Values(s.keydb.DoCtx(ctx, "XREAD",
+		"COUNT", redisEventFollowCount,
+		"BLOCK", timeout.Milliseconds(),
+		"STREAMS", s.key(topic), lastID,
+	))
+	if errors.Is(err, redis.ErrNil) {
+		return make([]*Event, 0), nil
+	} else if err != nil {
+		return nil, fmt.Errorf("follow events: %w", err)
+	}
+
+	// The reply holds a [key, entries] pair for the one stream read.
+	if len(reply) == 0 {
+		return make([]*Event, 0), nil
+	}
+
+	stream, err := redis.Values(reply[0], nil)
+	if err != nil || len(stream) != 2 {
+		return nil, fmt.Errorf("follow events: unexpected reply %v", reply[0])
+	}
+
+	entries, err := redis.Values(stream[1], nil)
+	if err != nil {
+		return nil, fmt.Errorf("follow events: %w", err)
+	}
+
+	return parseStreamEntries(entries)
+}
+
+// parseStreamEntries converts the [id, [field, value, ...]] entries of a stream reply to events.
+func parseStreamEntries(entries []any) ([]*Event, error) {
+	events := make([]*Event, 0, len(entries))
+	for _, entry := range entries {
+		parts, err := redis.Values(entry, nil)
+		if err != nil || len(parts) != 2 {
+			return nil, fmt.Errorf("parse event: unexpected entry %v", entry)
+		}
+
+		id, err := redis.String(parts[0], nil)
+		if err != nil {
+			return nil, fmt.Errorf("parse event id: %w", err)
+		}
+
+		fields, err := redis.StringMap(parts[1], nil)
+		if err != nil {
+			return nil, fmt.Errorf("parse event %s: %w", id, err)
+		}
+
+		ev := &Event{
+			ID:    id,
+			Event: fields[redisEventFieldEvent],
+			Data:  fields[redisEventFieldData],
+		}
+		if retry, err := strconv.ParseInt(fields[redisEventFieldRetry], 10, 64); err == nil {
+			ev.Retry = time.Duration(retry) * time.Millisecond
+		}
+
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+// validStreamID returns true if the ID is a Redis stream ID, such as "1700000000000-0".
+func validStreamID(id string) bool {
+	ms, seq, ok := strings.Cut(id, "-")
+	if !ok {
+		return false
+	}
+
+	if _, err := strconv.ParseUint(ms, 10, 64); err != nil {
+		return false
+	}
+	_, err := strconv.ParseUint(seq, 10, 64)
+	return err == nil
+}
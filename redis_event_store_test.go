@@ -0,0 +1,179 @@
+package uhttp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/jacobbrewer1/goredis"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisEventStore_Append(t *testing.T) {
+	pool := goredis.NewMockPool(t)
+	pool.On("DoCtx", mock.Anything, "XADD", "sse:orders", "MAXLEN", "~", 100, "*",
+		"event", "created", "data", "{}", "retry", int64(2000)).
+		Return([]byte("1700000000000-0"), nil)
+
+	store := NewRedisEventStore(pool, 100)
+
+	ev := &Event{Event: "created", Data: "{}", Retry: 2 * time.Second}
+	require.NoError(t, store.Append(context.Background(), "orders", ev))
+	require.Equal(t, "1700000000000-0", ev.ID)
+}
+
+func TestRedisEventStore_Since(t *testing.T) {
+	pool := goredis.NewMockPool(t)
+	pool.On("DoCtx", mock.Anything, "XRANGE", "sse:orders", "(1700000000000-0", "+", "COUNT", 100).
+		Return([]any{
+			[]any{[]byte("1700000000000-1"), []any{[]byte("event"), []byte(""), []byte("data"), []byte("a")}},
+			[]any{[]byte("1700000000001-0"), []any{[]byte("event"), []byte("created"), []byte("data"), []byte("b"), []byte("retry"), []byte("500")}},
+		}, nil)
+
+	store := NewRedisEventStore(pool, 100)
+
+	events, err := store.Since(context.Background(), "orders", "1700000000000-0")
+	require.NoError(t, err)
+	require.Equal(t, []*Event{
+		{ID: "1700000000000-1", Data: "a"},
+		{ID: "1700000000001-0", Event: "created", Data: "b", Retry: 500 * time.Millisecond},
+	}, events)
+
+	// IDs that are not stream IDs are not sent to Redis.
+	events, err = store.Since(context.Background(), "orders", "42")
+	require.NoError(t, err)
+	require.Empty(t, events)
+}
+
+func TestRedisEventStore_Follow(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   any
+		err     error
+		want    []*Event
+		wantErr string
+	}{
+		{
+			name: "events",
+			reply: []any{
+				[]any{[]byte("sse:orders"), []any{
+					[]any{[]byte("1700000000000-1"), []any{[]byte("event"), []byte(""), []byte("data"), []byte("a")}},
+				}},
+			},
+			want: []*Event{{ID: "1700000000000-1", Data: "a"}},
+		},
+		{
+			name: "timeout",
+			err:  redis.ErrNil,
+			want: []*Event{},
+		},
+		{
+			name:    "error",
+			err:     errors.New("connection refused"),
+			wantErr: "follow events: connection refused",
+		},
+		{
+			name:    "malformed reply",
+			reply:   []any{[]any{[]byte("sse:orders")}},
+			wantErr: "follow events: unexpected reply [[115 115 101 58 111 114 100 101 114 115]]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := goredis.NewMockPool(t)
+			pool.On("DoCtx", mock.Anything, "XREAD", "COUNT", 100, "BLOCK", int64(5000), "STREAMS", "sse:orders", "1700000000000-0").
+				Return(tt.reply, tt.err)
+
+			store := NewRedisEventStore(pool, 100).(EventFollower)
+
+			events, err := store.Follow(context.Background(), "orders", "1700000000000-0", 5*time.Second)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, events)
+		})
+	}
+}
+
+func TestRedisEventStore_LastID(t *testing.T) {
+	pool, _ := newMiniredisPool(t)
+	ctx := context.Background()
+	store := NewRedisEventStore(pool, 100).(EventFollower)
+
+	id, err := store.LastID(ctx, "orders")
+	require.NoError(t, err)
+	require.Equal(t, "0-0", id)
+
+	for _, data := range []string{"a", "b"} {
+		require.NoError(t, store.(EventStore).Append(ctx, "orders", &Event{Data: data}))
+	}
+
+	events, err := store.Follow(ctx, "orders", "0-0", time.Millisecond)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	id, err = store.LastID(ctx, "orders")
+	require.NoError(t, err)
+	require.Equal(t, events[1].ID, id)
+
+	// Nothing has been stored after the last event.
+	events, err = store.Follow(ctx, "orders", id, time.Millisecond)
+	require.NoError(t, err)
+	require.Empty(t, events)
+}
+
+func TestRedisEventStore_KeyPrefix(t *testing.T) {
+	pool, mr := newMiniredisPool(t)
+	ctx := context.Background()
+	store := NewRedisEventStore(pool, 100, WithEventStoreKeyPrefix("svc:sse:"))
+	other := NewRedisEventStore(pool, 100)
+
+	require.NoError(t, store.Append(ctx, "orders", &Event{Data: "a"}))
+	require.True(t, mr.Exists("svc:sse:orders"))
+	require.False(t, mr.Exists("sse:orders"))
+
+	// A store with another prefix does not see the events.
+	id, err := other.(EventFollower).LastID(ctx, "orders")
+	require.NoError(t, err)
+	require.Equal(t, "0-0", id)
+
+	events, err := store.Since(ctx, "orders", "0-0")
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+}
+
+func TestRedisEventStore_Broker(t *testing.T) {
+	pool, _ := newMiniredisPool(t)
+	ctx := context.Background()
+	store := NewRedisEventStore(pool, 100)
+	require.NoError(t, store.Append(ctx, "orders", &Event{Data: "before subscribing"}))
+
+	// Two brokers sharing the store stand in for two instances.
+	publisher := NewSSEBroker(WithEventStore(store))
+	subscriber := NewSSEBroker(WithEventStore(store))
+
+	_, sub, err := subscriber.subscribe(ctx, "orders", "")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		subscriber.unsubscribe("orders", sub)
+	})
+
+	for _, data := range []string{"a", "b", "c"} {
+		require.NoError(t, publisher.Publish(ctx, "orders", &Event{Data: data}))
+	}
+
+	for _, want := range []string{"a", "b", "c"} {
+		select {
+		case ev := <-sub.events:
+			require.Equal(t, want, ev.Data)
+		case <-time.After(time.Second):
+			require.Fail(t, "published event was not delivered")
+		}
+	}
+}
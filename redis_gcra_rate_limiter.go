@@ -0,0 +1,125 @@
+package uhttp
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/jacobbrewer1/goredis"
+)
+
+// gcraScript implements the Generic Cell Rate Algorithm. Only the theoretical arrival time (TAT) of the next request is
+// stored, which expires once the bucket would have refilled completely.
+//
+// KEYS[1] - the key holding the theoretical arrival time.
+// ARGV[1] - the current time in microseconds.
+// ARGV[2] - the emission interval (time to refill one token) in microseconds.
+// ARGV[3] - the bucket size.
+// ARGV[4] - the cost of the request.
+var gcraScript = redis.NewScript(1, `
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local burst = tonumber(ARGV[3])
+local cost = tonumber(ARGV[4])
+
+local tat = tonumber(redis.call('GET', key) or ARGV[1])
+if tat < now then
+	tat = now
+end
+
+local new_tat = tat + interval * cost
+local allow_at = new_tat - burst * interval
+if allow_at > now then
+	local retry_after = 0
+	if cost <= burst then
+		retry_after = allow_at - now
+	end
+	return {0, math.floor((now - tat) / interval) + burst, retry_after, tat - now}
+end
+
+redis.call('SET', key, string.format('%d', new_tat), 'PX', math.ceil((new_tat - now) / 1000))
+return {1, math.floor((now - new_tat) / interval) + burst, 0, new_tat - now}
+`)
+
+// gcraDrainScript takes tokens from a bucket that is never refilled, which is how rate.Limiter treats a rate of zero.
+// The tokens left are stored without an expiry, as they never change otherwise.
+//
+// KEYS[1] - the key holding the tokens left.
+// ARGV[1] - the bucket size.
+// ARGV[2] - the cost of the request.
+var gcraDrainScript = redis.NewScript(1, `
+local remaining = tonumber(redis.call('GET', KEYS[1]) or ARGV[1])
+local cost = tonumber(ARGV[2])
+if cost > remaining then
+	return {0, remaining, 0, 0}
+end
+
+remaining = remaining - cost
+redis.call('SET', KEYS[1], remaining)
+return {1, remaining, 0, 0}
+`)
+
+// redisGCRARateLimiter is a token bucket rate limiter backed by Redis. It behaves the same as the in-memory rate
+// limiter, refilling at rps up to a bucket size of burst, but shares the bucket across every instance using the same
+// Redis server.
+type redisGCRARateLimiter struct {
+	*redisRateLimiter
+}
+
+// NewRedisGCRARateLimiter creates a new rate limiter using the Generic Cell Rate Algorithm.
+//
+// Tokens are refilled at rps up to a maximum of burst, matching the behaviour of NewRateLimiter. A rps of rate.Inf
+// allows every request. As with rate.Limiter, a rps of zero or less lets each key take the burst once and then rejects
+// it for good; the tokens left for the key are kept at "<prefix>gcra_drain:<key>" without an expiry.
+func NewRedisGCRARateLimiter(keydb goredis.Pool, rps float64, burst int, opts ...RateLimiterOption) RateLimiter {
+	return &redisGCRARateLimiter{
+		redisRateLimiter: newRedisRateLimiter(keydb, rps, burst, opts...),
+	}
+}
+
+// Allow returns true if the request is allowed.
+func (r *redisGCRARateLimiter) Allow(key string) bool {
+	return r.AllowN(key, 1)
+}
+
+// AllowN returns true if a request costing n units of the quota is allowed.
+func (r *redisGCRARateLimiter) AllowN(key string, n int) bool {
+	decision, err := r.Take(context.Background(), key, n)
+	return r.allowed(key, decision, err)
+}
+
+// Take consumes cost units of the quota for the key and returns the decision.
+func (r *redisGCRARateLimiter) Take(ctx context.Context, key string, cost int) (*RateLimitDecision, error) {
+	cost = max(cost, 1)
+
+	switch {
+	case math.IsInf(r.rps, 1):
+		return &RateLimitDecision{
+			Allowed:   true,
+			Limit:     r.burst,
+			Remaining: r.burst,
+			Reset:     r.clock.Now(),
+		}, nil
+	case r.rps <= 0:
+		return r.take(ctx, key, cost, func(now time.Time) (*RateLimitDecision, error) {
+			return r.takeScript(ctx, now, gcraDrainScript, r.redisKey("gcra_drain", key), r.burst, cost)
+		})
+	}
+
+	return r.take(ctx, key, cost, func(now time.Time) (*RateLimitDecision, error) {
+		return r.takeScript(ctx, now, gcraScript,
+			r.redisKey("gcra", key),
+			now.UnixMicro(),
+			r.emissionInterval().Microseconds(),
+			r.burst,
+			cost,
+		)
+	})
+}
+
+// emissionInterval returns the time taken to refill a single token.
+func (r *redisGCRARateLimiter) emissionInterval() time.Duration {
+	return max(time.Duration(float64(time.Second)/r.rps), time.Microsecond)
+}
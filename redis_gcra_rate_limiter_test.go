@@ -0,0 +1,112 @@
+package uhttp
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jacobbrewer1/uhttp/uhttptest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisGCRARateLimiter(t *testing.T) {
+	pool, m := newMiniredisPool(t)
+	start := time.Unix(1_700_000_000, 0)
+	clock := uhttptest.NewFakeClock(start)
+
+	// A token is refilled every 500ms, up to 3.
+	rl := NewRedisGCRARateLimiter(pool, 2, 3, WithClock(clock))
+
+	steps := []struct {
+		name    string
+		advance time.Duration
+		cost    int
+		want    *RateLimitDecision
+	}{
+		{
+			name: "first request",
+			cost: 1,
+			want: &RateLimitDecision{Allowed: true, Limit: 3, Remaining: 2, Reset: start.Add(500 * time.Millisecond)},
+		},
+		{
+			name: "cost above one empties the bucket",
+			cost: 2,
+			want: &RateLimitDecision{Allowed: true, Limit: 3, Remaining: 0, Reset: start.Add(1500 * time.Millisecond)},
+		},
+		{
+			name: "denied until a token is refilled",
+			cost: 1,
+			want: &RateLimitDecision{Allowed: false, Limit: 3, Remaining: 0, Reset: start.Add(1500 * time.Millisecond), RetryAfter: 500 * time.Millisecond},
+		},
+		{
+			name: "cost above the burst is never allowed",
+			cost: 4,
+			want: &RateLimitDecision{Allowed: false, Limit: 3, Remaining: 0, Reset: start.Add(1500 * time.Millisecond)},
+		},
+		{
+			name:    "refilled one token",
+			advance: 500 * time.Millisecond,
+			cost:    1,
+			want:    &RateLimitDecision{Allowed: true, Limit: 3, Remaining: 0, Reset: start.Add(2 * time.Second)},
+		},
+		{
+			name: "denied for less than a token",
+			cost: 2,
+			want: &RateLimitDecision{Allowed: false, Limit: 3, Remaining: 0, Reset: start.Add(2 * time.Second), RetryAfter: time.Second},
+		},
+		{
+			name:    "bucket refilled completely",
+			advance: 10 * time.Second,
+			cost:    3,
+			want:    &RateLimitDecision{Allowed: true, Limit: 3, Remaining: 0, Reset: start.Add(12 * time.Second)},
+		},
+	}
+
+	for _, step := range steps {
+		clock.Advance(step.advance)
+
+		got, err := rl.Take(context.Background(), "key", step.cost)
+		require.NoError(t, err, step.name)
+		require.Equal(t, step.want, got, step.name)
+	}
+
+	// The TAT expires once the bucket would have refilled completely.
+	require.Equal(t, 1500*time.Millisecond, m.TTL("rate_limit:gcra:key"))
+}
+
+func TestRedisGCRARateLimiter_Unlimited(t *testing.T) {
+	pool, m := newMiniredisPool(t)
+	allowAll := NewRedisGCRARateLimiter(pool, math.Inf(1), 1)
+
+	for range 5 {
+		require.True(t, allowAll.Allow("key"))
+	}
+
+	// Redis is not needed.
+	require.Empty(t, m.Keys())
+}
+
+func TestRedisGCRARateLimiter_Parity(t *testing.T) {
+	for _, rps := range []float64{0, -1, 2} {
+		t.Run(strconv.FormatFloat(rps, 'f', -1, 64), func(t *testing.T) {
+			pool, _ := newMiniredisPool(t)
+			clock := uhttptest.NewFakeClock(time.Unix(1_700_000_000, 0))
+
+			local := NewRateLimiter(rps, 3, WithClock(clock))
+			shared := NewRedisGCRARateLimiter(pool, rps, 3, WithClock(clock))
+
+			for i, cost := range []int{1, 2, 1, 4, 1, 1} {
+				want, err := local.Take(context.Background(), "key", cost)
+				require.NoError(t, err)
+
+				got, err := shared.Take(context.Background(), "key", cost)
+				require.NoError(t, err)
+				require.Equal(t, want, got, "request %d", i)
+
+				clock.Advance(time.Second)
+			}
+		})
+	}
+}
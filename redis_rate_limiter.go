@@ -3,34 +3,106 @@ package uhttp
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"math"
 	"time"
 
 	"github.com/gomodule/redigo/redis"
 	"github.com/jacobbrewer1/goredis"
 )
 
+var (
+	// errCircuitOpen is returned while the circuit breaker is stopping calls to Redis.
+	errCircuitOpen = errors.New("rate limiter circuit breaker is open")
+)
+
+const (
+	// redisRateLimitKeyPrefix is the default prefix applied to every key the Redis rate limiters write.
+	redisRateLimitKeyPrefix = "rate_limit:"
+)
+
+// RateLimiterFailureMode decides how the Redis rate limiters answer when Redis is unavailable.
+type RateLimiterFailureMode int
+
+const (
+	// RateLimiterFailClosed returns the Redis error, so the request is rejected.
+	RateLimiterFailClosed RateLimiterFailureMode = iota
+
+	// RateLimiterFailOpen allows the request.
+	RateLimiterFailOpen
+
+	// RateLimiterFailLocal checks the request against an in-memory limiter local to this instance.
+	RateLimiterFailLocal
+)
+
+// fixedWindowScript counts the requests made in the current window, starting the window on the first request.
+//
+// KEYS[1] - the counter for the window.
+// ARGV[1] - the maximum number of requests in the window.
+// ARGV[2] - the cost of the request.
+// ARGV[3] - the window size in milliseconds.
+var fixedWindowScript = redis.NewScript(1, `
+local key = KEYS[1]
+local limit = tonumber(ARGV[1])
+local cost = tonumber(ARGV[2])
+
+local count = tonumber(redis.call('GET', key) or '0')
+local allowed = 0
+if count + cost <= limit then
+	count = redis.call('INCRBY', key, cost)
+	allowed = 1
+end
+
+local ttl = redis.call('PTTL', key)
+if ttl < 0 then
+	redis.call('PEXPIRE', key, ARGV[3])
+	ttl = tonumber(ARGV[3])
+end
+
+local retry_after = 0
+if allowed == 0 and cost <= limit then
+	retry_after = ttl * 1000
+end
+
+return {allowed, limit - count, retry_after, ttl * 1000}
+`)
+
 // redisRateLimiter is a rate limiter that uses Redis to store the rate limit.
 type redisRateLimiter struct {
-	keydb  goredis.Pool
-	window time.Duration
+	keydb goredis.Pool
+
+	// breaker stops calls to Redis while it is unavailable. It is nil when no circuit breaker is configured.
+	breaker *circuitBreaker
+
+	// fallback is the in-memory limiter used by RateLimiterFailLocal.
+	fallback *rateLimiter
 
 	*rateLimiter
 }
 
 // NewRedisRateLimiter creates a new rate limiter.
 func NewRedisRateLimiter(keydb goredis.Pool, rps float64, burst int, opts ...RateLimiterOption) RateLimiter {
+	return newRedisRateLimiter(keydb, rps, burst, opts...)
+}
+
+func newRedisRateLimiter(keydb goredis.Pool, rps float64, burst int, opts ...RateLimiterOption) *redisRateLimiter {
 	if burst == 0 || float64(burst) < rps {
 		burst = int(rps)
 	}
 
 	rl := &redisRateLimiter{
 		rateLimiter: &rateLimiter{
-			rps:   rps,
-			burst: burst,
+			limiterConfig: limiterConfig{
+				clock:     systemClock{},
+				keyPrefix: redisRateLimitKeyPrefix,
+			},
+			name:   defaultRateLimiterName,
+			window: time.Second,
+			rps:    rps,
+			burst:  burst,
 		},
-		keydb:  keydb,
-		window: time.Second,
+		keydb: keydb,
 	}
 
 	for _, opt := range opts {
@@ -41,34 +113,152 @@ func NewRedisRateLimiter(keydb goredis.Pool, rps float64, burst int, opts ...Rat
 		rl.ctx = context.Background()
 	}
 
+	if rl.window <= 0 {
+		rl.window = time.Second
+	}
+
+	if rl.breakerThreshold > 0 {
+		rl.breaker = newCircuitBreaker(rl.breakerThreshold, rl.breakerCooldown, func(from, to circuitState) {
+			rl.log(slog.LevelWarn, "rate limiter circuit breaker changed state",
+				slog.String(loggingKeyFrom, from.String()),
+				slog.String(loggingKeyTo, to.String()),
+			)
+		})
+	}
+
+	if rl.failureMode == RateLimiterFailLocal {
+		scale := rl.fallbackScale
+		if scale <= 0 {
+			scale = 1
+		}
+
+		rl.fallback = &rateLimiter{
+			limiterConfig: limiterConfig{
+				l:     rl.l,
+				clock: rl.clock,
+			},
+			ctx:     rl.ctx,
+			name:    rl.name + fallbackRateLimiterSuffix,
+			metrics: rl.metrics,
+			rps:     rl.rps * scale,
+			burst:   max(int(math.Ceil(float64(rl.burst)*scale)), 1),
+			idleTTL: rl.idleTTL,
+			maxKeys: rl.maxKeys,
+		}
+		rl.fallback.init()
+	}
+
 	return rl
 }
 
 // Allow returns true if the request is allowed.
 func (r *redisRateLimiter) Allow(key string) bool {
-	ctx := context.Background()
-	redisKey := "rate_limit:" + key
-
-	// Try to set key with value 1 and 1-second TTL if not exists
-	setReply, err := redis.String(r.keydb.DoCtx(ctx, "SET", redisKey, 1, "EX", int(r.window.Seconds()), "NX"))
-	if err != nil && !errors.Is(err, redis.ErrNil) {
-		r.log(slog.LevelError, "failed to set rate limit key", slog.String(loggingKeyKey, redisKey), slog.String(loggingKeyError, err.Error()))
-		return false
-	} else if setReply == "OK" {
-		// Key was created — allow request
-		return true
+	return r.AllowN(key, 1)
+}
+
+// AllowN returns true if a request costing n units of the quota is allowed.
+func (r *redisRateLimiter) AllowN(key string, n int) bool {
+	decision, err := r.Take(context.Background(), key, n)
+	return r.allowed(key, decision, err)
+}
+
+// Take consumes cost units of the quota for the key and returns the decision.
+func (r *redisRateLimiter) Take(ctx context.Context, key string, cost int) (*RateLimitDecision, error) {
+	return r.take(ctx, key, cost, func(now time.Time) (*RateLimitDecision, error) {
+		return r.takeScript(ctx, now, fixedWindowScript,
+			r.redisKey("", key),
+			r.burst,
+			max(cost, 1),
+			r.window.Milliseconds(),
+		)
+	})
+}
+
+// take makes the Redis check through the circuit breaker, answering with the failure mode if Redis is unavailable.
+func (r *redisRateLimiter) take(ctx context.Context, key string, cost int, check func(now time.Time) (*RateLimitDecision, error)) (*RateLimitDecision, error) {
+	now := r.clock.Now()
+	if !r.breaker.allow(now) {
+		return r.fail(ctx, key, cost, errCircuitOpen)
+	}
+
+	decision, err := check(now)
+	switch {
+	case err == nil:
+		r.breaker.success()
+		r.metrics.observeDecision(r.name, decision, nil)
+		return decision, nil
+	case ctx.Err() != nil:
+		// The caller gave up, which says nothing about the health of Redis.
+		r.breaker.abandon()
+		r.metrics.observeDecision(r.name, nil, err)
+		return nil, err
 	}
 
-	// Key exists, increment count
-	reply, err := r.keydb.DoCtx(ctx, "INCR", redisKey)
+	r.breaker.failure(now)
+	return r.fail(ctx, key, cost, err)
+}
+
+// fail answers the request according to the failure mode when Redis could not be used. The Redis failure is counted
+// separately from the decision, so that outages are visible whatever the failure mode answers.
+func (r *redisRateLimiter) fail(ctx context.Context, key string, cost int, err error) (*RateLimitDecision, error) {
+	r.metrics.observeRedisFailure(r.name)
+
+	switch r.failureMode {
+	case RateLimiterFailOpen:
+		r.log(slog.LevelWarn, "rate limiter unavailable, allowing request", slog.String(loggingKeyKey, key), slog.String(loggingKeyError, err.Error()))
+		decision := &RateLimitDecision{
+			Allowed:   true,
+			Limit:     r.burst,
+			Remaining: r.burst,
+			Reset:     r.clock.Now(),
+		}
+		r.metrics.observeDecision(r.name, decision, nil)
+		return decision, nil
+	case RateLimiterFailLocal:
+		// The fallback records its decisions under its own policy label.
+		r.log(slog.LevelWarn, "rate limiter unavailable, using local limiter", slog.String(loggingKeyKey, key), slog.String(loggingKeyError, err.Error()))
+		return r.fallback.Take(ctx, key, cost)
+	default:
+		r.metrics.observeDecision(r.name, nil, err)
+		return nil, err
+	}
+}
+
+// takeScript runs a rate limit script that replies with the allowed flag, the remaining quota, the retry after and the
+// reset, with both durations in microseconds.
+func (r *redisRateLimiter) takeScript(ctx context.Context, now time.Time, script *redis.Script, keysAndArgs ...any) (*RateLimitDecision, error) {
+	reply, err := redis.Int64s(r.runScript(ctx, script, keysAndArgs...))
 	if err != nil {
-		return false
+		return nil, err
+	} else if len(reply) != 4 {
+		return nil, fmt.Errorf("unexpected rate limit script reply: %v", reply)
 	}
 
-	count, ok := reply.(int64)
-	if !ok {
-		return false
+	return &RateLimitDecision{
+		Allowed:    reply[0] == 1,
+		Limit:      r.burst,
+		Remaining:  max(int(reply[1]), 0),
+		Reset:      now.Add(time.Duration(reply[3]) * time.Microsecond),
+		RetryAfter: time.Duration(reply[2]) * time.Microsecond,
+	}, nil
+}
+
+// runScript executes the Lua script atomically on the Redis server. The script is sent by hash and only uploaded when
+// the server does not have it cached yet.
+func (r *redisRateLimiter) runScript(ctx context.Context, script *redis.Script, keysAndArgs ...any) (any, error) {
+	conn := r.keydb.Conn()
+	defer func() {
+		if err := conn.Close(); err != nil {
+			r.log(slog.LevelWarn, "failed to close redis connection", slog.String(loggingKeyError, err.Error()))
+		}
+	}()
+
+	start := r.clock.Now()
+	reply, err := script.DoContext(ctx, conn, keysAndArgs...)
+	r.metrics.observeRedis(r.name, r.clock.Now().Sub(start))
+	if err != nil {
+		return nil, fmt.Errorf("run rate limit script: %w", err)
 	}
 
-	return count <= int64(r.burst)
+	return reply, nil
 }
@@ -0,0 +1,232 @@
+package uhttp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jacobbrewer1/goredis"
+	"github.com/jacobbrewer1/uhttp/uhttptest"
+	"github.com/stretchr/testify/require"
+)
+
+var errRedisUnavailable = errors.New("redis unavailable")
+
+// newMiniredisPool starts an embedded Redis server for the test, returning a pool connected to it and the server.
+func newMiniredisPool(t *testing.T) (goredis.Pool, *miniredis.Miniredis) {
+	t.Helper()
+
+	m := miniredis.RunT(t)
+	pool, err := goredis.NewPool(goredis.WithAddress(m.Addr()), goredis.WithNetwork("tcp"))
+	require.NoError(t, err)
+
+	return pool, m
+}
+
+// unavailableConn is a Redis connection that fails every command.
+type unavailableConn struct{}
+
+func (unavailableConn) Close() error { return nil }
+
+func (unavailableConn) Err() error { return errRedisUnavailable }
+
+func (unavailableConn) Do(string, ...any) (any, error) { return nil, errRedisUnavailable }
+
+func (unavailableConn) DoContext(context.Context, string, ...any) (any, error) {
+	return nil, errRedisUnavailable
+}
+
+func (unavailableConn) Send(string, ...any) error { return errRedisUnavailable }
+
+func (unavailableConn) Flush() error { return errRedisUnavailable }
+
+func (unavailableConn) Receive() (any, error) { return nil, errRedisUnavailable }
+
+func (unavailableConn) ReceiveContext(context.Context) (any, error) { return nil, errRedisUnavailable }
+
+func TestRedisRateLimiter_FailureMode(t *testing.T) {
+	constructors := map[string]RedisRateLimiterConstructor{
+		"fixed window":           NewRedisRateLimiter,
+		"gcra":                   NewRedisGCRARateLimiter,
+		"sliding window log":     NewRedisSlidingWindowLogRateLimiter,
+		"sliding window counter": NewRedisSlidingWindowCounterRateLimiter,
+	}
+
+	for name, newLimiter := range constructors {
+		t.Run(name, func(t *testing.T) {
+			t.Run("fail closed", func(t *testing.T) {
+				pool := goredis.NewMockPool(t)
+				pool.On("Conn").Return(unavailableConn{})
+
+				rl := newLimiter(pool, 1, 2)
+
+				_, err := rl.Take(context.Background(), "key", 1)
+				require.ErrorIs(t, err, errRedisUnavailable)
+				require.False(t, rl.Allow("key"))
+			})
+
+			t.Run("fail open", func(t *testing.T) {
+				pool := goredis.NewMockPool(t)
+				pool.On("Conn").Return(unavailableConn{})
+
+				rl := newLimiter(pool, 1, 2, WithFailureMode(RateLimiterFailOpen))
+
+				for range 5 {
+					require.True(t, rl.Allow("key"))
+				}
+			})
+
+			t.Run("fail local", func(t *testing.T) {
+				pool := goredis.NewMockPool(t)
+				pool.On("Conn").Return(unavailableConn{})
+
+				rl := newLimiter(pool, 1, 4, WithFailureMode(RateLimiterFailLocal), WithLocalFallbackScale(0.5))
+
+				decision, err := rl.Take(context.Background(), "key", 1)
+				require.NoError(t, err)
+				require.True(t, decision.Allowed)
+				require.Equal(t, 2, decision.Limit)
+
+				require.True(t, rl.Allow("key"))
+				require.False(t, rl.Allow("key"))
+			})
+
+			t.Run("circuit breaker", func(t *testing.T) {
+				pool := goredis.NewMockPool(t)
+				pool.On("Conn").Return(unavailableConn{}).Times(2)
+
+				rl := newLimiter(pool, 1, 2, WithCircuitBreaker(2, time.Minute))
+
+				// Once the breaker opens Redis is no longer called.
+				for range 5 {
+					require.False(t, rl.Allow("key"))
+				}
+
+				_, err := rl.Take(context.Background(), "key", 1)
+				require.ErrorIs(t, err, errCircuitOpen)
+			})
+		})
+	}
+}
+
+// cancelledConn is a Redis connection that fails every command with the error of its context.
+type cancelledConn struct {
+	unavailableConn
+}
+
+func (cancelledConn) DoContext(ctx context.Context, _ string, _ ...any) (any, error) {
+	return nil, ctx.Err()
+}
+
+func TestRedisRateLimiter_CancelledProbe(t *testing.T) {
+	pool := goredis.NewMockPool(t)
+	pool.On("Conn").Return(unavailableConn{}).Once()
+	pool.On("Conn").Return(cancelledConn{}).Once()
+	pool.On("Conn").Return(unavailableConn{}).Once()
+
+	clock := uhttptest.NewFakeClock(time.Now())
+	rl := NewRedisRateLimiter(pool, 1, 2, WithClock(clock), WithCircuitBreaker(1, time.Minute))
+
+	_, err := rl.Take(context.Background(), "key", 1)
+	require.ErrorIs(t, err, errRedisUnavailable)
+
+	// The caller gives up on the probe after the cooldown.
+	clock.Advance(time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = rl.Take(ctx, "key", 1)
+	require.ErrorIs(t, err, context.Canceled)
+
+	// The next call probes Redis again rather than finding the circuit stuck half-open.
+	_, err = rl.Take(context.Background(), "key", 1)
+	require.ErrorIs(t, err, errRedisUnavailable)
+
+	// The failed probe opens the circuit.
+	_, err = rl.Take(context.Background(), "key", 1)
+	require.ErrorIs(t, err, errCircuitOpen)
+}
+
+// recordingConn is a Redis connection that records the arguments of every command before failing it.
+type recordingConn struct {
+	unavailableConn
+	args *[][]any
+}
+
+func (c recordingConn) DoContext(_ context.Context, _ string, args ...any) (any, error) {
+	*c.args = append(*c.args, args)
+	return nil, errRedisUnavailable
+}
+
+func TestRedisRateLimiter_Keys(t *testing.T) {
+	tests := []struct {
+		name       string
+		newLimiter RedisRateLimiterConstructor
+		opts       []RateLimiterOption
+		wantKeys   []any
+		wantWindow any
+	}{
+		{
+			name:       "fixed window defaults",
+			newLimiter: NewRedisRateLimiter,
+			wantKeys:   []any{"rate_limit:key"},
+			wantWindow: int64(1000),
+		},
+		{
+			name:       "fixed window",
+			newLimiter: NewRedisRateLimiter,
+			opts:       []RateLimiterOption{WithKeyPrefix("svc:"), WithHashTag(), WithWindow(time.Minute)},
+			wantKeys:   []any{"svc:{key}"},
+			wantWindow: int64(60000),
+		},
+		{
+			name:       "gcra",
+			newLimiter: NewRedisGCRARateLimiter,
+			opts:       []RateLimiterOption{WithKeyPrefix("svc:"), WithHashTag()},
+			wantKeys:   []any{"svc:gcra:{key}"},
+		},
+		{
+			name:       "sliding window log",
+			newLimiter: NewRedisSlidingWindowLogRateLimiter,
+			opts:       []RateLimiterOption{WithKeyPrefix("svc:"), WithWindow(time.Minute)},
+			wantKeys:   []any{"svc:sliding_log:key"},
+			wantWindow: int64(60000000),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args [][]any
+			pool := goredis.NewMockPool(t)
+			pool.On("Conn").Return(recordingConn{args: &args})
+
+			_, err := tt.newLimiter(pool, 1, 2, tt.opts...).Take(context.Background(), "key", 1)
+			require.ErrorIs(t, err, errRedisUnavailable)
+
+			// The script is sent by hash as EVALSHA sha numkeys keys... args...
+			require.Len(t, args, 1)
+			require.Equal(t, len(tt.wantKeys), args[0][1])
+			require.Equal(t, tt.wantKeys, args[0][2:2+len(tt.wantKeys)])
+			if tt.wantWindow != nil {
+				require.Contains(t, args[0][2+len(tt.wantKeys):], tt.wantWindow)
+			}
+		})
+	}
+}
+
+func TestRedisSlidingWindowCounterRateLimiter_HashTag(t *testing.T) {
+	var args [][]any
+	pool := goredis.NewMockPool(t)
+	pool.On("Conn").Return(recordingConn{args: &args})
+
+	clock := uhttptest.NewFakeClock(time.Unix(120, 0))
+	rl := NewRedisSlidingWindowCounterRateLimiter(pool, 1, 2, WithClock(clock), WithHashTag(), WithWindow(time.Minute))
+
+	_, err := rl.Take(context.Background(), "key", 1)
+	require.ErrorIs(t, err, errRedisUnavailable)
+
+	// Both windows share the hash tag, so they hash to the same Redis Cluster slot.
+	require.Len(t, args, 1)
+	require.Equal(t, []any{"rate_limit:sliding_counter:{key}:2", "rate_limit:sliding_counter:{key}:1"}, args[0][2:4])
+}
@@ -0,0 +1,187 @@
+package uhttp
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/google/uuid"
+	"github.com/jacobbrewer1/goredis"
+)
+
+// slidingWindowLogScript records the request in a sorted set scored by its timestamp, dropping any entries that have
+// fallen out of the window before counting.
+//
+// KEYS[1] - the sorted set holding the request log.
+// ARGV[1] - the current time in microseconds.
+// ARGV[2] - the window size in microseconds.
+// ARGV[3] - the maximum number of requests in the window.
+// ARGV[4] - the cost of the request.
+// ARGV[5] - a unique member for this request.
+var slidingWindowLogScript = redis.NewScript(1, `
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+local cost = tonumber(ARGV[4])
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+local count = redis.call('ZCARD', key)
+
+local allowed = 0
+local retry_after = 0
+if count + cost <= limit then
+	for i = 1, cost do
+		redis.call('ZADD', key, now, ARGV[5] .. ':' .. i)
+	end
+	count = count + cost
+	allowed = 1
+elseif cost <= limit then
+	local index = count + cost - limit - 1
+	local oldest = redis.call('ZRANGE', key, index, index, 'WITHSCORES')
+	retry_after = tonumber(oldest[2]) + window - now
+end
+
+local reset = 0
+local newest = redis.call('ZRANGE', key, -1, -1, 'WITHSCORES')
+if newest[2] then
+	reset = tonumber(newest[2]) + window - now
+end
+
+redis.call('PEXPIRE', key, math.ceil(window / 1000))
+return {allowed, limit - count, retry_after, reset}
+`)
+
+// slidingWindowCounterScript approximates a sliding window by weighting the previous fixed window's count by how much
+// of it still overlaps the sliding window.
+//
+// KEYS[1] - the counter for the current fixed window.
+// KEYS[2] - the counter for the previous fixed window.
+// ARGV[1] - the maximum number of requests in the window.
+// ARGV[2] - the window size in microseconds.
+// ARGV[3] - the time elapsed since the start of the current fixed window in microseconds.
+// ARGV[4] - the cost of the request.
+var slidingWindowCounterScript = redis.NewScript(2, `
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local elapsed = tonumber(ARGV[3])
+local cost = tonumber(ARGV[4])
+
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+local previous = tonumber(redis.call('GET', KEYS[2]) or '0')
+local weighted = previous * ((window - elapsed) / window) + current
+
+local allowed = 0
+local retry_after = 0
+if weighted + cost <= limit then
+	current = redis.call('INCRBY', KEYS[1], cost)
+	redis.call('PEXPIRE', KEYS[1], math.ceil(window * 2 / 1000))
+	weighted = weighted + cost
+	allowed = 1
+elseif cost <= limit then
+	if current + cost <= limit then
+		-- Wait for enough of the previous window to slide out.
+		retry_after = window - elapsed - (limit - current - cost) * window / previous
+	else
+		-- Wait for the current window to become the previous window, and enough of it to slide out.
+		retry_after = 2 * window - elapsed - (limit - cost) * window / current
+	end
+end
+
+local reset = 0
+if current > 0 then
+	reset = 2 * window - elapsed
+elseif previous > 0 then
+	reset = window - elapsed
+end
+
+return {allowed, math.floor(limit - weighted), math.ceil(retry_after), reset}
+`)
+
+// redisSlidingWindowLogRateLimiter is a rate limiter that keeps a log of every request in the window in Redis. It is
+// exact, at the cost of storing one entry per request.
+type redisSlidingWindowLogRateLimiter struct {
+	*redisRateLimiter
+}
+
+// NewRedisSlidingWindowLogRateLimiter creates a new rate limiter that allows burst requests in any sliding window.
+//
+// Every request is recorded in a Redis sorted set, and the check and record happen in a single Lua script so the limit
+// holds across every instance sharing the Redis server.
+func NewRedisSlidingWindowLogRateLimiter(keydb goredis.Pool, rps float64, burst int, opts ...RateLimiterOption) RateLimiter {
+	return &redisSlidingWindowLogRateLimiter{
+		redisRateLimiter: newRedisRateLimiter(keydb, rps, burst, opts...),
+	}
+}
+
+// Allow returns true if the request is allowed.
+func (r *redisSlidingWindowLogRateLimiter) Allow(key string) bool {
+	return r.AllowN(key, 1)
+}
+
+// AllowN returns true if a request costing n units of the quota is allowed.
+func (r *redisSlidingWindowLogRateLimiter) AllowN(key string, n int) bool {
+	decision, err := r.Take(context.Background(), key, n)
+	return r.allowed(key, decision, err)
+}
+
+// Take consumes cost units of the quota for the key and returns the decision.
+func (r *redisSlidingWindowLogRateLimiter) Take(ctx context.Context, key string, cost int) (*RateLimitDecision, error) {
+	return r.take(ctx, key, cost, func(now time.Time) (*RateLimitDecision, error) {
+		return r.takeScript(ctx, now, slidingWindowLogScript,
+			r.redisKey("sliding_log", key),
+			now.UnixMicro(),
+			r.window.Microseconds(),
+			r.burst,
+			max(cost, 1),
+			strconv.FormatInt(now.UnixNano(), 10)+"-"+uuid.NewString(),
+		)
+	})
+}
+
+// redisSlidingWindowCounterRateLimiter is a rate limiter that approximates a sliding window from the counts of the
+// current and previous fixed windows. It uses constant memory per key.
+type redisSlidingWindowCounterRateLimiter struct {
+	*redisRateLimiter
+}
+
+// NewRedisSlidingWindowCounterRateLimiter creates a new rate limiter that allows approximately burst requests in any
+// sliding window.
+//
+// Only two counters are stored per key, and the check and increment happen in a single Lua script so the limit holds
+// across every instance sharing the Redis server.
+func NewRedisSlidingWindowCounterRateLimiter(keydb goredis.Pool, rps float64, burst int, opts ...RateLimiterOption) RateLimiter {
+	return &redisSlidingWindowCounterRateLimiter{
+		redisRateLimiter: newRedisRateLimiter(keydb, rps, burst, opts...),
+	}
+}
+
+// Allow returns true if the request is allowed.
+func (r *redisSlidingWindowCounterRateLimiter) Allow(key string) bool {
+	return r.AllowN(key, 1)
+}
+
+// AllowN returns true if a request costing n units of the quota is allowed.
+func (r *redisSlidingWindowCounterRateLimiter) AllowN(key string, n int) bool {
+	decision, err := r.Take(context.Background(), key, n)
+	return r.allowed(key, decision, err)
+}
+
+// Take consumes cost units of the quota for the key and returns the decision.
+func (r *redisSlidingWindowCounterRateLimiter) Take(ctx context.Context, key string, cost int) (*RateLimitDecision, error) {
+	return r.take(ctx, key, cost, func(now time.Time) (*RateLimitDecision, error) {
+		redisKey := r.redisKey("sliding_counter", key)
+		window := r.window.Microseconds()
+		current := now.UnixMicro() / window
+
+		return r.takeScript(ctx, now, slidingWindowCounterScript,
+			redisKey+":"+strconv.FormatInt(current, 10),
+			redisKey+":"+strconv.FormatInt(current-1, 10),
+			r.burst,
+			window,
+			now.UnixMicro()-current*window,
+			max(cost, 1),
+		)
+	})
+}
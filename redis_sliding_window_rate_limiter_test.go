@@ -0,0 +1,123 @@
+package uhttp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jacobbrewer1/uhttp/uhttptest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisSlidingWindowLogRateLimiter(t *testing.T) {
+	pool, _ := newMiniredisPool(t)
+	start := time.Unix(1_700_000_000, 0)
+	clock := uhttptest.NewFakeClock(start)
+	rl := NewRedisSlidingWindowLogRateLimiter(pool, 3, 3, WithClock(clock))
+
+	steps := []struct {
+		name    string
+		advance time.Duration
+		cost    int
+		want    *RateLimitDecision
+	}{
+		{
+			name: "first request",
+			cost: 1,
+			want: &RateLimitDecision{Allowed: true, Limit: 3, Remaining: 2, Reset: start.Add(time.Second)},
+		},
+		{
+			name: "cost above one",
+			cost: 2,
+			want: &RateLimitDecision{Allowed: true, Limit: 3, Remaining: 0, Reset: start.Add(time.Second)},
+		},
+		{
+			name:    "denied until the oldest request leaves the window",
+			advance: 100 * time.Millisecond,
+			cost:    1,
+			want:    &RateLimitDecision{Allowed: false, Limit: 3, Remaining: 0, Reset: start.Add(time.Second), RetryAfter: 900 * time.Millisecond},
+		},
+		{
+			name: "cost above the limit is never allowed",
+			cost: 4,
+			want: &RateLimitDecision{Allowed: false, Limit: 3, Remaining: 0, Reset: start.Add(time.Second)},
+		},
+		{
+			name:    "window expired",
+			advance: 900 * time.Millisecond,
+			cost:    3,
+			want:    &RateLimitDecision{Allowed: true, Limit: 3, Remaining: 0, Reset: start.Add(2 * time.Second)},
+		},
+	}
+
+	for _, step := range steps {
+		clock.Advance(step.advance)
+
+		got, err := rl.Take(context.Background(), "key", step.cost)
+		require.NoError(t, err, step.name)
+		require.Equal(t, step.want, got, step.name)
+	}
+}
+
+func TestRedisSlidingWindowCounterRateLimiter(t *testing.T) {
+	pool, m := newMiniredisPool(t)
+	start := time.Unix(1_700_000_000, 0)
+	clock := uhttptest.NewFakeClock(start)
+	rl := NewRedisSlidingWindowCounterRateLimiter(pool, 4, 4, WithClock(clock))
+
+	steps := []struct {
+		name    string
+		advance time.Duration
+		cost    int
+		want    *RateLimitDecision
+	}{
+		{
+			name: "cost above one",
+			cost: 3,
+			want: &RateLimitDecision{Allowed: true, Limit: 4, Remaining: 1, Reset: start.Add(2 * time.Second)},
+		},
+		{
+			name: "denied until the current window slides out",
+			cost: 2,
+			want: &RateLimitDecision{Allowed: false, Limit: 4, Remaining: 1, Reset: start.Add(2 * time.Second), RetryAfter: 1333334 * time.Microsecond},
+		},
+		{
+			name: "fills the window",
+			cost: 1,
+			want: &RateLimitDecision{Allowed: true, Limit: 4, Remaining: 0, Reset: start.Add(2 * time.Second)},
+		},
+		{
+			name:    "half of the previous window counts",
+			advance: 1500 * time.Millisecond,
+			cost:    2,
+			want:    &RateLimitDecision{Allowed: true, Limit: 4, Remaining: 0, Reset: start.Add(3 * time.Second)},
+		},
+		{
+			name: "denied until enough of the previous window slides out",
+			cost: 1,
+			want: &RateLimitDecision{Allowed: false, Limit: 4, Remaining: 0, Reset: start.Add(3 * time.Second), RetryAfter: 250 * time.Millisecond},
+		},
+		{
+			name: "cost above the limit is never allowed",
+			cost: 5,
+			want: &RateLimitDecision{Allowed: false, Limit: 4, Remaining: 0, Reset: start.Add(3 * time.Second)},
+		},
+		{
+			name:    "both windows expired",
+			advance: 1500 * time.Millisecond,
+			cost:    4,
+			want:    &RateLimitDecision{Allowed: true, Limit: 4, Remaining: 0, Reset: start.Add(5 * time.Second)},
+		},
+	}
+
+	for _, step := range steps {
+		clock.Advance(step.advance)
+
+		got, err := rl.Take(context.Background(), "key", step.cost)
+		require.NoError(t, err, step.name)
+		require.Equal(t, step.want, got, step.name)
+	}
+
+	// Each counter outlives the window after it, so it can still be read as the previous window.
+	require.Equal(t, 2*time.Second, m.TTL("rate_limit:sliding_counter:key:1700000003"))
+}
@@ -14,6 +14,11 @@ type ResponseWriter struct {
 	bytesWritten    uint64
 	isStatusWritten bool
 	startTime       time.Time
+	clock           Clock
+
+	// uncompressedBytesWritten is the number of bytes written before compression, when the response is compressed.
+	uncompressedBytesWritten uint64
+	isCompressed             bool
 
 	defaultStatusCode     int
 	defaultHeaders        map[string]string
@@ -24,7 +29,7 @@ type ResponseWriter struct {
 func NewResponseWriter(w http.ResponseWriter, opts ...WriterOpt) *ResponseWriter {
 	rw := &ResponseWriter{
 		ResponseWriter: w,
-		startTime:      time.Now().UTC(),
+		clock:          systemClock{},
 		defaultHeaders: map[string]string{
 			HeaderContentType: ContentTypeJSON,
 		},
@@ -35,9 +40,28 @@ func NewResponseWriter(w http.ResponseWriter, opts ...WriterOpt) *ResponseWriter
 		opt(rw)
 	}
 
+	rw.startTime = rw.now().UTC()
+
 	return rw
 }
 
+// responseWriterOf returns the *ResponseWriter the response writer is or wraps, found through the Unwrap method of each
+// wrapper, or nil if there is none.
+func responseWriterOf(w http.ResponseWriter) *ResponseWriter {
+	for w != nil {
+		if rw, ok := w.(*ResponseWriter); ok {
+			return rw
+		}
+
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+		w = unwrapper.Unwrap()
+	}
+	return nil
+}
+
 // Write writes the data to the connection as part of an HTTP reply.
 func (c *ResponseWriter) Write(p []byte) (bytes int, err error) {
 	c.writeDefaultHeaders()
@@ -65,6 +89,24 @@ func (c *ResponseWriter) WriteHeader(code int) {
 	c.statusCode = code
 }
 
+// Flush sends any buffered data to the client, writing the header first if it has not been written. It does nothing
+// more if the underlying writer cannot flush.
+func (c *ResponseWriter) Flush() {
+	_ = c.FlushError()
+}
+
+// FlushError is Flush, returning an error that wraps http.ErrNotSupported if the underlying writer cannot flush. It is
+// used by http.ResponseController.
+func (c *ResponseWriter) FlushError() error {
+	c.WriteHeader(c.defaultStatusCode)
+	return http.NewResponseController(c.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying http.ResponseWriter, so that http.ResponseController can reach it.
+func (c *ResponseWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
 // StatusCode returns the status code.
 func (c *ResponseWriter) StatusCode() int {
 	if !c.isStatusWritten || c.statusCode == 0 {
@@ -73,11 +115,26 @@ func (c *ResponseWriter) StatusCode() int {
 	return c.statusCode
 }
 
-// BytesWritten returns the number of bytes written.
+// BytesWritten returns the number of bytes written. When the response is compressed by the Compress middleware this is
+// the compressed size, as sent to the client.
 func (c *ResponseWriter) BytesWritten() uint64 {
 	return c.bytesWritten
 }
 
+// UncompressedBytesWritten returns the number of bytes written before compression. It is the same as BytesWritten when
+// the response is not compressed.
+func (c *ResponseWriter) UncompressedBytesWritten() uint64 {
+	if !c.isCompressed {
+		return c.bytesWritten
+	}
+	return c.uncompressedBytesWritten
+}
+
+// IsCompressed returns true if the response is compressed by the Compress middleware.
+func (c *ResponseWriter) IsCompressed() bool {
+	return c.isCompressed
+}
+
 // IsHeaderWritten returns true if the header has been written.
 func (c *ResponseWriter) IsHeaderWritten() bool {
 	return c.isStatusWritten
@@ -85,7 +142,24 @@ func (c *ResponseWriter) IsHeaderWritten() bool {
 
 // GetRequestDuration gets the duration of the request
 func (c *ResponseWriter) GetRequestDuration() time.Duration {
-	return time.Since(c.startTime)
+	return c.now().Sub(c.startTime)
+}
+
+// now returns the current time from the clock of the writer, or from the system clock if it has none, as for a writer
+// that was not created by NewResponseWriter.
+func (c *ResponseWriter) now() time.Time {
+	if c.clock == nil {
+		return systemClock{}.Now()
+	}
+	return c.clock.Now()
+}
+
+// addUncompressedBytes records bytes written to the response before they were compressed.
+func (c *ResponseWriter) addUncompressedBytes(n int) {
+	c.isCompressed = true
+	if n > 0 {
+		c.uncompressedBytesWritten += uint64(n)
+	}
 }
 
 func (c *ResponseWriter) writeDefaultHeaders() {
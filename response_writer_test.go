@@ -0,0 +1,28 @@
+package uhttp
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jacobbrewer1/uhttp/uhttptest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseWriter_GetRequestDuration(t *testing.T) {
+	clock := uhttptest.NewFakeClock(time.Now())
+	w := NewResponseWriter(httptest.NewRecorder(), WithWriterClock(clock))
+
+	require.Zero(t, w.GetRequestDuration())
+
+	clock.Advance(1500 * time.Millisecond)
+	require.Equal(t, 1500*time.Millisecond, w.GetRequestDuration())
+}
+
+func TestResponseWriter_GetRequestDuration_NoClock(t *testing.T) {
+	w := &ResponseWriter{ResponseWriter: httptest.NewRecorder(), startTime: time.Now()}
+
+	require.NotPanics(t, func() {
+		require.GreaterOrEqual(t, w.GetRequestDuration(), time.Duration(0))
+	})
+}
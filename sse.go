@@ -0,0 +1,163 @@
+package uhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// HeaderLastEventID is sent by EventSource clients when they reconnect, holding the ID of the last event received.
+	HeaderLastEventID = "Last-Event-ID"
+
+	// ContentTypeEventStream is the media type of Server-Sent Events.
+	ContentTypeEventStream = "text/event-stream"
+
+	headerCacheControl   = "Cache-Control"
+	headerAccelBuffering = "X-Accel-Buffering"
+
+	// sseHeartbeatComment is the comment sent as a heartbeat.
+	sseHeartbeatComment = "heartbeat"
+)
+
+var (
+	errSSENotSupported = errors.New("server-sent events need a response writer that can flush")
+	errSSEInvalidField = errors.New("server-sent event fields cannot contain line breaks")
+)
+
+// Event is a Server-Sent Event.
+type Event struct {
+	// ID is the ID of the event, which the client sends back in the Last-Event-ID header when it reconnects. Events
+	// published through an SSEBroker are given their ID by its EventStore.
+	ID string `json:"id,omitempty"`
+
+	// Event is the type of the event. Empty is the default type, "message".
+	Event string `json:"event,omitempty"`
+
+	// Data is the payload of the event. Each line is sent as its own data field.
+	Data string `json:"data"`
+
+	// Retry asks the client to wait this long before reconnecting. Zero leaves the client's delay unchanged.
+	Retry time.Duration `json:"retry,omitempty"`
+}
+
+// NewJSONEvent creates an event of the type with v encoded as JSON as its data.
+func NewJSONEvent(event string, v any) (*Event, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("encode event data: %w", err)
+	}
+
+	return &Event{
+		Event: event,
+		Data:  string(data),
+	}, nil
+}
+
+// SSEWriter writes Server-Sent Events to a response, flushing each one to the client as it is written. It is not safe
+// for concurrent use.
+type SSEWriter struct {
+	w  *ResponseWriter
+	rc *http.ResponseController
+}
+
+// NewSSEWriter starts a Server-Sent Events response, sending the headers straight away so that the client knows the
+// stream is open. It returns an error if the response writer cannot be flushed, as events would otherwise be held
+// back.
+func NewSSEWriter(w http.ResponseWriter) (*SSEWriter, error) {
+	rw, ok := w.(*ResponseWriter)
+	if !ok {
+		rw = NewResponseWriter(w)
+	}
+
+	rw.Header().Set(HeaderContentType, ContentTypeEventStream)
+	rw.Header().Set(headerCacheControl, "no-cache")
+	rw.Header().Set(headerAccelBuffering, "no")
+	rw.WriteHeader(http.StatusOK)
+
+	sse := &SSEWriter{
+		w:  rw,
+		rc: http.NewResponseController(rw),
+	}
+
+	if err := sse.rc.Flush(); err != nil {
+		if errors.Is(err, http.ErrNotSupported) {
+			return nil, errSSENotSupported
+		}
+		return nil, fmt.Errorf("flush event stream: %w", err)
+	}
+
+	return sse, nil
+}
+
+// Send writes the event and flushes it to the client.
+func (s *SSEWriter) Send(ev *Event) error {
+	if strings.ContainsAny(ev.ID, "\r\n") || strings.ContainsAny(ev.Event, "\r\n") {
+		return errSSEInvalidField
+	}
+
+	var sb strings.Builder
+	if ev.ID != "" {
+		sb.WriteString("id: " + ev.ID + "\n")
+	}
+	if ev.Event != "" {
+		sb.WriteString("event: " + ev.Event + "\n")
+	}
+	if ev.Retry > 0 {
+		sb.WriteString("retry: " + strconv.FormatInt(ev.Retry.Milliseconds(), 10) + "\n")
+	}
+
+	for line := range sseLines(ev.Data) {
+		sb.WriteString("data: " + line + "\n")
+	}
+	sb.WriteString("\n")
+
+	return s.write(sb.String())
+}
+
+// Retry asks the client to wait d before reconnecting, without sending an event.
+func (s *SSEWriter) Retry(d time.Duration) error {
+	return s.write("retry: " + strconv.FormatInt(d.Milliseconds(), 10) + "\n\n")
+}
+
+// Comment writes a comment, which clients ignore. Each line is sent as its own comment.
+func (s *SSEWriter) Comment(text string) error {
+	var sb strings.Builder
+	for line := range sseLines(text) {
+		sb.WriteString(": " + line + "\n")
+	}
+	sb.WriteString("\n")
+
+	return s.write(sb.String())
+}
+
+// Heartbeat writes a comment so that proxies do not close an idle connection, and so that a disconnected client is
+// noticed.
+func (s *SSEWriter) Heartbeat() error {
+	return s.Comment(sseHeartbeatComment)
+}
+
+// sseLineBreaks replaces every line break the event stream format recognises with a line feed.
+var sseLineBreaks = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// sseLines splits the text into lines at CRLF, LF or a lone CR, which clients all treat as line breaks, so that no line
+// of the text can be read as a field of its own.
+func sseLines(text string) iter.Seq[string] {
+	return strings.SplitSeq(sseLineBreaks.Replace(text), "\n")
+}
+
+// write writes the text and flushes it to the client.
+func (s *SSEWriter) write(text string) error {
+	if _, err := s.w.Write([]byte(text)); err != nil {
+		return fmt.Errorf("write event stream: %w", err)
+	}
+	if err := s.rc.Flush(); err != nil {
+		return fmt.Errorf("flush event stream: %w", err)
+	}
+	return nil
+}
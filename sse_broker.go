@@ -0,0 +1,469 @@
+package uhttp
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultSSEBufferSize is the default number of events held for each subscriber before it is dropped as too slow.
+	defaultSSEBufferSize = 64
+
+	// defaultSSEHeartbeat is the default interval between heartbeats.
+	defaultSSEHeartbeat = 15 * time.Second
+
+	// defaultSSEReplaySize is the default number of events the in-memory store holds for each topic.
+	defaultSSEReplaySize = 256
+
+	// defaultSSEFollowTimeout is how long the broker waits for new events from an EventFollower before asking again.
+	defaultSSEFollowTimeout = 5 * time.Second
+
+	// sseFollowRetryDelay is how long the broker waits before following a topic again after an error.
+	sseFollowRetryDelay = time.Second
+)
+
+// EventStore holds the recent events of each topic, so that clients can replay the events they missed when they
+// reconnect with a Last-Event-ID.
+type EventStore interface {
+	// Append stores the event on the topic, setting its ID. IDs increase with each event.
+	Append(ctx context.Context, topic string, ev *Event) error
+
+	// Since returns the events stored on the topic after the event with the ID, oldest first. It returns no events
+	// if the ID is empty or malformed.
+	Since(ctx context.Context, topic, lastID string) ([]*Event, error)
+}
+
+// EventFollower is implemented by an EventStore shared between instances. The broker follows the topics of its
+// subscribers through the store rather than delivering the events it publishes itself, so that subscribers receive the
+// events published on every instance.
+type EventFollower interface {
+	// LastID returns the ID of the last event stored on the topic, which Follow waits for the events after. When the
+	// topic has no events it returns an ID that comes before any event.
+	LastID(ctx context.Context, topic string) (string, error)
+
+	// Follow waits up to the timeout for events to be stored on the topic after the event with the ID, returning them
+	// oldest first, or no events if the timeout passes.
+	Follow(ctx context.Context, topic, lastID string, timeout time.Duration) ([]*Event, error)
+}
+
+// memoryEventStore is an EventStore that holds a bounded number of events for each topic in memory.
+type memoryEventStore struct {
+	mu sync.Mutex
+
+	// size is the number of events held for each topic.
+	size int
+
+	// lastID is the ID of the last event appended on any topic.
+	lastID uint64
+
+	// topics holds the events of each topic, oldest first.
+	topics map[string][]*Event
+}
+
+// NewMemoryEventStore creates an EventStore that holds the last size events of each topic in memory.
+func NewMemoryEventStore(size int) EventStore {
+	return &memoryEventStore{
+		size:   size,
+		topics: make(map[string][]*Event),
+	}
+}
+
+// Append stores the event on the topic, dropping the oldest event if the topic is full.
+func (s *memoryEventStore) Append(_ context.Context, topic string, ev *Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	ev.ID = strconv.FormatUint(s.lastID, 10)
+
+	stored := *ev
+	events := append(s.topics[topic], &stored)
+	if len(events) > s.size {
+		events = events[len(events)-s.size:]
+	}
+	s.topics[topic] = events
+
+	return nil
+}
+
+// Since returns the events held on the topic after the event with the ID.
+func (s *memoryEventStore) Since(_ context.Context, topic, lastID string) ([]*Event, error) {
+	last, err := strconv.ParseUint(lastID, 10, 64)
+	if err != nil {
+		return make([]*Event, 0), nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := make([]*Event, 0)
+	for _, ev := range s.topics[topic] {
+		if id, _ := strconv.ParseUint(ev.ID, 10, 64); id > last {
+			stored := *ev
+			events = append(events, &stored)
+		}
+	}
+	return events, nil
+}
+
+// SSEBrokerOption configures an SSEBroker.
+type SSEBrokerOption func(*SSEBroker)
+
+// WithEventStore sets where the broker keeps events for replay. The default holds the last 256 events of each topic
+// in memory.
+func WithEventStore(store EventStore) SSEBrokerOption {
+	return func(b *SSEBroker) {
+		b.store = store
+	}
+}
+
+// WithSSEBufferSize sets how many events are held for a subscriber that is not keeping up. A subscriber that falls
+// further behind is disconnected, and replays what it missed when it reconnects. The default is 64.
+func WithSSEBufferSize(size int) SSEBrokerOption {
+	return func(b *SSEBroker) {
+		b.bufferSize = size
+	}
+}
+
+// WithSSEHeartbeat sets the interval between heartbeats sent to idle subscribers. The default is 15 seconds; zero
+// disables heartbeats.
+func WithSSEHeartbeat(interval time.Duration) SSEBrokerOption {
+	return func(b *SSEBroker) {
+		b.heartbeat = interval
+	}
+}
+
+// WithSSERetry asks clients to wait d before reconnecting.
+func WithSSERetry(d time.Duration) SSEBrokerOption {
+	return func(b *SSEBroker) {
+		b.retry = d
+	}
+}
+
+// WithSSEBrokerLogger sets the logger used by the broker.
+func WithSSEBrokerLogger(l *slog.Logger) SSEBrokerOption {
+	return func(b *SSEBroker) {
+		b.l = l
+	}
+}
+
+// SSEBroker fans out the events published on a topic to the subscribers of the topic, and replays the events a
+// subscriber missed when it reconnects with a Last-Event-ID.
+type SSEBroker struct {
+	mu sync.Mutex
+
+	// store holds the events for replay.
+	store EventStore
+
+	// topics holds the subscribers of each topic.
+	topics map[string]*sseTopic
+
+	bufferSize int
+	heartbeat  time.Duration
+	retry      time.Duration
+	l          *slog.Logger
+}
+
+// sseTopic is a topic with subscribers.
+type sseTopic struct {
+	subscribers map[*sseSubscriber]struct{}
+
+	// stopFollowing stops following the topic through an EventFollower.
+	stopFollowing context.CancelFunc
+}
+
+// sseSubscriber receives the events of a topic.
+type sseSubscriber struct {
+	events chan *Event
+
+	// dropped is closed when the subscriber falls too far behind.
+	dropped chan struct{}
+}
+
+// NewSSEBroker creates a new SSEBroker.
+func NewSSEBroker(opts ...SSEBrokerOption) *SSEBroker {
+	b := &SSEBroker{
+		store:      NewMemoryEventStore(defaultSSEReplaySize),
+		topics:     make(map[string]*sseTopic),
+		bufferSize: defaultSSEBufferSize,
+		heartbeat:  defaultSSEHeartbeat,
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return b
+}
+
+func (b *SSEBroker) log(level slog.Level, msg string, args ...any) {
+	if b.l == nil {
+		return
+	}
+
+	b.l.Log(context.Background(), level, msg, args...) // nolint:sloglint // Handler around the messages passed in to prevent panics on nil logger
+}
+
+// Publish stores the event on the topic, setting its ID, and sends it to the subscribers of the topic.
+func (b *SSEBroker) Publish(ctx context.Context, topic string, ev *Event) error {
+	// Events are delivered through the store when it is followed, or they would arrive twice.
+	if _, ok := b.store.(EventFollower); ok {
+		return b.store.Append(ctx, topic, ev)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.store.Append(ctx, topic, ev); err != nil {
+		return err
+	}
+
+	published := *ev
+	b.deliver(topic, &published)
+	return nil
+}
+
+// deliver sends the event to the subscribers of the topic, dropping any that are too far behind. The caller must hold
+// the lock.
+func (b *SSEBroker) deliver(topic string, ev *Event) {
+	t, ok := b.topics[topic]
+	if !ok {
+		return
+	}
+
+	for sub := range t.subscribers {
+		select {
+		case sub.events <- ev:
+		default:
+			b.log(slog.LevelWarn, "Dropping slow event subscriber", slog.String(loggingKeyTopic, topic))
+			b.remove(topic, sub)
+			close(sub.dropped)
+		}
+	}
+}
+
+// subscribe adds a subscriber to the topic, returning it with the events after lastID to replay. The subscriber
+// receives the events published from now on, and must be removed with unsubscribe.
+func (b *SSEBroker) subscribe(ctx context.Context, topic, lastID string) ([]*Event, *sseSubscriber, error) {
+	sub := &sseSubscriber{
+		events:  make(chan *Event, b.bufferSize),
+		dropped: make(chan struct{}),
+	}
+
+	// The store is read without holding the lock, so that a slow store does not hold up the other topics.
+	follower, following := b.store.(EventFollower)
+	var followID string
+	if following {
+		// A topic that is not followed yet is followed from the event stored last before subscribing, so that none of
+		// the events stored from now on are missed, however long the follower takes to start.
+		var err error
+		followID, err = follower.LastID(ctx, topic)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	b.mu.Lock()
+	t, ok := b.topics[topic]
+	if !ok {
+		t = &sseTopic{
+			subscribers: make(map[*sseSubscriber]struct{}),
+		}
+
+		if following {
+			followCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+			t.stopFollowing = cancel
+			go b.follow(followCtx, follower, topic, followID)
+		}
+
+		b.topics[topic] = t
+	}
+	t.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+
+	if lastID == "" {
+		return make([]*Event, 0), sub, nil
+	}
+
+	// The subscriber is added before the replay is read, so no event falls between the two. The events in both are
+	// sent once, as serve skips the events up to the last one replayed.
+	replay, err := b.store.Since(ctx, topic, lastID)
+	if err != nil {
+		b.unsubscribe(topic, sub)
+		return nil, nil, err
+	}
+
+	return replay, sub, nil
+}
+
+// unsubscribe removes the subscriber from the topic.
+func (b *SSEBroker) unsubscribe(topic string, sub *sseSubscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.remove(topic, sub)
+}
+
+// remove removes the subscriber from the topic, forgetting the topic when it has no subscribers left. The caller must
+// hold the lock.
+func (b *SSEBroker) remove(topic string, sub *sseSubscriber) {
+	t, ok := b.topics[topic]
+	if !ok {
+		return
+	}
+
+	delete(t.subscribers, sub)
+	if len(t.subscribers) > 0 {
+		return
+	}
+
+	delete(b.topics, topic)
+	if t.stopFollowing != nil {
+		t.stopFollowing()
+	}
+}
+
+// follow delivers the events stored on the topic by any instance after the event with the ID, until the context is
+// cancelled.
+func (b *SSEBroker) follow(ctx context.Context, follower EventFollower, topic, lastID string) {
+	for ctx.Err() == nil {
+		events, err := follower.Follow(ctx, topic, lastID, defaultSSEFollowTimeout)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			b.log(slog.LevelError, "Error following event topic",
+				slog.String(loggingKeyTopic, topic),
+				slog.String(loggingKeyError, err.Error()),
+			)
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(sseFollowRetryDelay):
+			}
+			continue
+		}
+
+		b.mu.Lock()
+		for _, ev := range events {
+			b.deliver(topic, ev)
+			lastID = ev.ID
+		}
+		b.mu.Unlock()
+	}
+}
+
+// Handler returns a handler that streams the events of the topic named by topicFunc to the client, starting with any
+// events after the Last-Event-ID the client sent. The subscription is removed when the client disconnects.
+func (b *SSEBroker) Handler(topicFunc func(r *http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		topic := topicFunc(r)
+
+		replay, sub, err := b.subscribe(ctx, topic, r.Header.Get(HeaderLastEventID))
+		if err != nil {
+			b.log(slog.LevelError, "Error replaying events",
+				slog.String(loggingKeyTopic, topic),
+				slog.String(loggingKeyError, err.Error()),
+			)
+			ServiceUnavailableHandler().ServeHTTP(w, r)
+			return
+		}
+		defer b.unsubscribe(topic, sub)
+
+		sse, err := NewSSEWriter(w)
+		if err != nil {
+			b.log(slog.LevelError, "Error opening event stream", slog.String(loggingKeyError, err.Error()))
+			return
+		}
+
+		if err := b.serve(ctx, sse, replay, sub); err != nil && !errors.Is(err, context.Canceled) {
+			b.log(slog.LevelDebug, "Event stream closed",
+				slog.String(loggingKeyTopic, topic),
+				slog.String(loggingKeyError, err.Error()),
+			)
+		}
+	})
+}
+
+// serve writes the replayed events and then the events of the subscriber until the client disconnects or the
+// subscriber is dropped.
+func (b *SSEBroker) serve(ctx context.Context, sse *SSEWriter, replay []*Event, sub *sseSubscriber) error {
+	if b.retry > 0 {
+		if err := sse.Retry(b.retry); err != nil {
+			return err
+		}
+	}
+
+	lastID := ""
+	for _, ev := range replay {
+		if err := sse.Send(ev); err != nil {
+			return err
+		}
+		lastID = ev.ID
+	}
+
+	var heartbeat <-chan time.Time
+	if b.heartbeat > 0 {
+		ticker := time.NewTicker(b.heartbeat)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-sub.dropped:
+			return nil
+		case <-heartbeat:
+			if err := sse.Heartbeat(); err != nil {
+				return err
+			}
+		case ev := <-sub.events:
+			// Events published while the replay was read may have been replayed already.
+			if lastID != "" && compareEventIDs(ev.ID, lastID) <= 0 {
+				continue
+			}
+			if err := sse.Send(ev); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// compareEventIDs compares event IDs made of one or two numbers separated by a dash, such as "42" or Redis stream IDs
+// such as "1700000000000-3".
+func compareEventIDs(a, b string) int {
+	aMain, aSeq := splitEventID(a)
+	bMain, bSeq := splitEventID(b)
+
+	switch {
+	case aMain != bMain:
+		if aMain < bMain {
+			return -1
+		}
+		return 1
+	case aSeq < bSeq:
+		return -1
+	case aSeq > bSeq:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// splitEventID splits an event ID into its numbers. Malformed parts are zero.
+func splitEventID(id string) (main, seq uint64) {
+	mainPart, seqPart, _ := strings.Cut(id, "-")
+	main, _ = strconv.ParseUint(mainPart, 10, 64)
+	seq, _ = strconv.ParseUint(seqPart, 10, 64)
+	return main, seq
+}
@@ -0,0 +1,330 @@
+package uhttp
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// readEvents reads n events from an event stream, returning their id and data lines.
+func readEvents(t *testing.T, body *bufio.Reader, n int) []string {
+	t.Helper()
+
+	events := make([]string, 0, n)
+	var current []string
+	for len(events) < n {
+		line, err := body.ReadString('\n')
+		require.NoError(t, err)
+
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			if len(current) > 0 {
+				events = append(events, strings.Join(current, " "))
+			}
+			current = nil
+		case strings.HasPrefix(line, "id: "), strings.HasPrefix(line, "data: "):
+			current = append(current, line)
+		}
+	}
+	return events
+}
+
+// subscribeTo opens an event stream on the server. The broker subscribes the client before sending the headers, so the
+// subscription is in place once the response arrives.
+func subscribeTo(t *testing.T, url, topic, lastID string) *bufio.Reader {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"?topic="+topic, http.NoBody)
+	require.NoError(t, err)
+	if lastID != "" {
+		req.Header.Set(HeaderLastEventID, lastID)
+	}
+
+	resp, err := http.DefaultClient.Do(req) // nolint:bodyclose // Closed by cancelling the request
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, ContentTypeEventStream, resp.Header.Get(HeaderContentType))
+
+	return bufio.NewReader(resp.Body)
+}
+
+func topicFromQuery(r *http.Request) string {
+	return r.URL.Query().Get("topic")
+}
+
+func TestMemoryEventStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEventStore(2)
+
+	for _, data := range []string{"a", "b", "c"} {
+		require.NoError(t, store.Append(ctx, "orders", &Event{Data: data}))
+	}
+	require.NoError(t, store.Append(ctx, "users", &Event{Data: "d"}))
+
+	tests := []struct {
+		name   string
+		topic  string
+		lastID string
+		want   []*Event
+	}{
+		{name: "evicted", topic: "orders", lastID: "0", want: []*Event{{ID: "2", Data: "b"}, {ID: "3", Data: "c"}}},
+		{name: "after id", topic: "orders", lastID: "2", want: []*Event{{ID: "3", Data: "c"}}},
+		{name: "up to date", topic: "orders", lastID: "3", want: []*Event{}},
+		{name: "other topic", topic: "users", lastID: "1", want: []*Event{{ID: "4", Data: "d"}}},
+		{name: "malformed id", topic: "orders", lastID: "abc", want: []*Event{}},
+		{name: "unknown topic", topic: "missing", lastID: "0", want: []*Event{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.Since(ctx, tt.topic, tt.lastID)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSSEBroker(t *testing.T) {
+	ctx := context.Background()
+	b := NewSSEBroker(WithSSEHeartbeat(0))
+	srv := httptest.NewServer(b.Handler(topicFromQuery))
+	t.Cleanup(srv.Close) // Runs after the subscribers are closed
+
+	// Published before anyone subscribes, so only kept for replay.
+	require.NoError(t, b.Publish(ctx, "orders", &Event{Data: "first"}))
+	require.NoError(t, b.Publish(ctx, "orders", &Event{Data: "second"}))
+
+	live := subscribeTo(t, srv.URL, "orders", "")
+	replayed := subscribeTo(t, srv.URL, "orders", "1")
+
+	ev := &Event{Data: "third"}
+	require.NoError(t, b.Publish(ctx, "orders", ev))
+	require.Equal(t, "3", ev.ID)
+	require.NoError(t, b.Publish(ctx, "users", &Event{Data: "other"}))
+
+	require.Equal(t, []string{"id: 3 data: third"}, readEvents(t, live, 1))
+	require.Equal(t, []string{"id: 2 data: second", "id: 3 data: third"}, readEvents(t, replayed, 2))
+}
+
+func TestSSEBroker_Disconnect(t *testing.T) {
+	b := NewSSEBroker()
+	srv := httptest.NewServer(b.Handler(topicFromQuery))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?topic=orders", http.NoBody)
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Eventually(t, func() bool {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		return len(b.topics) == 1
+	}, time.Second, time.Millisecond)
+
+	cancel()
+
+	require.Eventually(t, func() bool {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		return len(b.topics) == 0
+	}, time.Second, time.Millisecond)
+}
+
+func TestSSEBroker_SlowSubscriber(t *testing.T) {
+	ctx := context.Background()
+	b := NewSSEBroker(WithSSEBufferSize(1))
+
+	_, sub, err := b.subscribe(ctx, "orders", "")
+	require.NoError(t, err)
+
+	require.NoError(t, b.Publish(ctx, "orders", &Event{Data: "first"}))
+	require.NoError(t, b.Publish(ctx, "orders", &Event{Data: "second"}))
+
+	select {
+	case <-sub.dropped:
+	default:
+		require.Fail(t, "slow subscriber was not dropped")
+	}
+	require.Empty(t, b.topics)
+
+	// Unsubscribing after being dropped is harmless.
+	b.unsubscribe("orders", sub)
+}
+
+// followedStore is an EventStore shared between instances, whose events arrive through Follow. Follow polls
+// briefly rather than for the timeout, so that the broker sees empty polls.
+type followedStore struct {
+	EventStore
+
+	mu     sync.Mutex
+	events []*Event
+	polls  int
+
+	// blocked holds up LastID for the topics in it until the channel is closed.
+	blocked map[string]chan struct{}
+}
+
+// add stores an event as if another instance had published it.
+func (s *followedStore) add(ev *Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, ev)
+}
+
+func (s *followedStore) LastID(_ context.Context, topic string) (string, error) {
+	if block, ok := s.blocked[topic]; ok {
+		<-block
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.events) == 0 {
+		return "0", nil
+	}
+	return s.events[len(s.events)-1].ID, nil
+}
+
+func (s *followedStore) Follow(ctx context.Context, _, lastID string, _ time.Duration) ([]*Event, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(time.Millisecond):
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.polls++
+	events := make([]*Event, 0)
+	for _, ev := range s.events {
+		if compareEventIDs(ev.ID, lastID) > 0 {
+			events = append(events, ev)
+		}
+	}
+	return events, nil
+}
+
+func (s *followedStore) pollCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.polls
+}
+
+func TestSSEBroker_Follower(t *testing.T) {
+	ctx := context.Background()
+	store := &followedStore{
+		EventStore: NewMemoryEventStore(defaultSSEReplaySize),
+	}
+	store.add(&Event{ID: "1", Data: "before subscribing"})
+	b := NewSSEBroker(WithEventStore(store), WithSSEHeartbeat(0))
+
+	replay, sub, err := b.subscribe(ctx, "orders", "")
+	require.NoError(t, err)
+	require.Empty(t, replay)
+
+	// An event stored before the follower first polls is still delivered.
+	store.add(&Event{ID: "2", Data: "before polling"})
+
+	// Published events are only delivered through the store.
+	require.NoError(t, b.Publish(ctx, "orders", &Event{Data: "local"}))
+
+	receive := func() *Event {
+		t.Helper()
+
+		select {
+		case ev := <-sub.events:
+			return ev
+		case <-time.After(time.Second):
+			require.Fail(t, "followed event was not delivered")
+			return nil
+		}
+	}
+	require.Equal(t, &Event{ID: "2", Data: "before polling"}, receive())
+
+	// An event stored after empty polls is delivered once.
+	polls := store.pollCount()
+	require.Eventually(t, func() bool {
+		return store.pollCount() >= polls+2
+	}, time.Second, time.Millisecond)
+
+	store.add(&Event{ID: "3", Data: "after empty polls"})
+	require.Equal(t, &Event{ID: "3", Data: "after empty polls"}, receive())
+
+	polls = store.pollCount()
+	require.Eventually(t, func() bool {
+		return store.pollCount() >= polls+2
+	}, time.Second, time.Millisecond)
+	require.Empty(t, sub.events)
+
+	b.unsubscribe("orders", sub)
+	require.Empty(t, b.topics)
+}
+
+func TestSSEBroker_SlowStore(t *testing.T) {
+	ctx := context.Background()
+	unblock := make(chan struct{})
+	store := &followedStore{
+		EventStore: NewMemoryEventStore(defaultSSEReplaySize),
+		blocked:    map[string]chan struct{}{"slow": unblock},
+	}
+	b := NewSSEBroker(WithEventStore(store), WithSSEHeartbeat(0))
+
+	var (
+		slow    *sseSubscriber
+		slowErr error
+	)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, slow, slowErr = b.subscribe(ctx, "slow", "")
+	}()
+
+	// Other topics are served while the store is slow to answer for one.
+	_, sub, err := b.subscribe(ctx, "orders", "")
+	require.NoError(t, err)
+	b.unsubscribe("orders", sub)
+
+	close(unblock)
+	<-done
+	require.NoError(t, slowErr)
+	b.unsubscribe("slow", slow)
+	require.Empty(t, b.topics)
+}
+
+func TestCompareEventIDs(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "1", b: "2", want: -1},
+		{a: "10", b: "9", want: 1},
+		{a: "5", b: "5", want: 0},
+		{a: "1700000000000-1", b: "1700000000000-0", want: 1},
+		{a: "1700000000000-1", b: "1700000000001-0", want: -1},
+		{a: "1700000000000-0", b: "1700000000000", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			require.Equal(t, tt.want, compareEventIDs(tt.a, tt.b))
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package uhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// plainWriter is a response writer that cannot flush.
+type plainWriter struct {
+	header http.Header
+}
+
+func (w *plainWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *plainWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (w *plainWriter) WriteHeader(int) {}
+
+func TestSSEWriter(t *testing.T) {
+	w := httptest.NewRecorder()
+	sse, err := NewSSEWriter(w)
+	require.NoError(t, err)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, ContentTypeEventStream, w.Header().Get(HeaderContentType))
+	require.Equal(t, "no-cache", w.Header().Get(headerCacheControl))
+	require.True(t, w.Flushed)
+
+	require.NoError(t, sse.Send(&Event{ID: "1", Event: "update", Data: "line one\r\nline two", Retry: 3 * time.Second}))
+	require.NoError(t, sse.Send(&Event{Data: "plain"}))
+	require.NoError(t, sse.Comment("hello"))
+	require.NoError(t, sse.Heartbeat())
+	require.NoError(t, sse.Retry(time.Second))
+
+	require.Equal(t, "id: 1\nevent: update\nretry: 3000\ndata: line one\ndata: line two\n\n"+
+		"data: plain\n\n"+
+		": hello\n\n"+
+		": heartbeat\n\n"+
+		"retry: 1000\n\n", w.Body.String())
+}
+
+func TestSSEWriter_LoneCR(t *testing.T) {
+	w := httptest.NewRecorder()
+	sse, err := NewSSEWriter(w)
+	require.NoError(t, err)
+
+	// A lone CR is a line break to clients, so it must not let the data or a comment start a field.
+	require.NoError(t, sse.Send(&Event{Data: "hello\rid: 999\revent: admin"}))
+	require.NoError(t, sse.Comment("hello\rretry: 1"))
+
+	require.Equal(t, "data: hello\ndata: id: 999\ndata: event: admin\n\n"+
+		": hello\n: retry: 1\n\n", w.Body.String())
+}
+
+func TestSSEWriter_InvalidField(t *testing.T) {
+	sse, err := NewSSEWriter(httptest.NewRecorder())
+	require.NoError(t, err)
+
+	require.ErrorIs(t, sse.Send(&Event{ID: "1\ndata: injected"}), errSSEInvalidField)
+	require.ErrorIs(t, sse.Send(&Event{Event: "a\rb"}), errSSEInvalidField)
+}
+
+func TestSSEWriter_NotFlushable(t *testing.T) {
+	_, err := NewSSEWriter(&plainWriter{header: make(http.Header)})
+	require.ErrorIs(t, err, errSSENotSupported)
+}
+
+func TestNewJSONEvent(t *testing.T) {
+	ev, err := NewJSONEvent("created", map[string]int{"id": 1})
+	require.NoError(t, err)
+	require.Equal(t, &Event{Event: "created", Data: `{"id":1}`}, ev)
+
+	_, err = NewJSONEvent("created", make(chan int))
+	require.Error(t, err)
+}
@@ -0,0 +1,272 @@
+package uhttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"time"
+)
+
+const (
+	// defaultStreamFlushEvery is the default number of records written between flushes.
+	defaultStreamFlushEvery = 64
+
+	// defaultStreamFlushInterval is the default longest time records are held before a flush.
+	defaultStreamFlushInterval = time.Second
+)
+
+// StreamErrorRecord is the final record of a stream that failed after the response had started. In NDJSON it is the
+// last line, and in a JSON array the last element.
+type StreamErrorRecord struct {
+	Error *HTTPError `json:"error"`
+}
+
+// StreamOption configures a streaming response.
+type StreamOption func(*streamer)
+
+type streamer struct {
+	// flushEvery is the number of records written between flushes.
+	flushEvery int
+
+	// flushInterval is the longest time since the last flush before the next record is flushed.
+	flushInterval time.Duration
+
+	// clock is used to time flushes.
+	clock Clock
+}
+
+// WithStreamFlushEvery flushes the response after every n records. The default is 64.
+func WithStreamFlushEvery(n int) StreamOption {
+	return func(s *streamer) {
+		s.flushEvery = n
+	}
+}
+
+// WithStreamFlushInterval flushes a record straight away when at least d has passed since the last flush, so that slow
+// streams are not held back waiting for enough records. The default is one second.
+func WithStreamFlushInterval(d time.Duration) StreamOption {
+	return func(s *streamer) {
+		s.flushInterval = d
+	}
+}
+
+// WithStreamClock sets the clock used to time flushes. The default is the system clock.
+func WithStreamClock(clock Clock) StreamOption {
+	return func(s *streamer) {
+		s.clock = clock
+	}
+}
+
+func newStreamer(opts ...StreamOption) *streamer {
+	s := &streamer{
+		flushEvery:    defaultStreamFlushEvery,
+		flushInterval: defaultStreamFlushInterval,
+		clock:         systemClock{},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// FromSeq adapts an iter.Seq, which cannot fail, for use with the stream helpers.
+func FromSeq[T any](seq iter.Seq[T]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for v := range seq {
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}
+}
+
+// FromChan adapts a channel for use with the stream helpers. The sequence ends when the channel is closed, or yields
+// the error of the context if it is done first.
+func FromChan[T any](ctx context.Context, ch <-chan T) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			select {
+			case <-ctx.Done():
+				var zero T
+				yield(zero, ctx.Err())
+				return
+			case v, ok := <-ch:
+				if !ok || !yield(v, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// StreamNDJSON writes each value of the sequence as a line of newline delimited JSON, flushing as it goes rather than
+// buffering the whole response.
+//
+// If the sequence yields an error, or a value cannot be encoded, the stream stops with a StreamErrorRecord as its last
+// line and the error in the X-Stream-Error trailer, and the error is returned. If the request context is done the
+// stream stops without a final record and the context error is returned.
+func StreamNDJSON[T any](w http.ResponseWriter, r *http.Request, status int, seq iter.Seq2[T, error], opts ...StreamOption) error {
+	return stream(newStreamer(opts...), w, r, status, false, seq)
+}
+
+// StreamJSONArray writes the values of the sequence as the elements of a JSON array, flushing as it goes rather than
+// buffering the whole response. The array is well-formed even if the stream fails.
+//
+// If the sequence yields an error, or a value cannot be encoded, the stream stops with a StreamErrorRecord as the last
+// element of the array and the error in the X-Stream-Error trailer, and the error is returned. If the request context
+// is done the stream stops without closing the array and the context error is returned.
+func StreamJSONArray[T any](w http.ResponseWriter, r *http.Request, status int, seq iter.Seq2[T, error], opts ...StreamOption) error {
+	return stream(newStreamer(opts...), w, r, status, true, seq)
+}
+
+// stream writes the sequence as NDJSON, or as a JSON array if array is true.
+func stream[T any](s *streamer, w http.ResponseWriter, r *http.Request, status int, array bool, seq iter.Seq2[T, error]) error {
+	ctx := r.Context()
+
+	contentType := ContentTypeNDJSON
+	if array {
+		contentType = ContentTypeJSON
+	}
+
+	w.Header().Set(HeaderContentType, contentType)
+	w.Header().Add(HeaderTrailer, HeaderStreamError)
+	w.WriteHeader(status)
+
+	sw := &streamWriter{
+		w:         w,
+		rc:        http.NewResponseController(w),
+		streamer:  s,
+		array:     array,
+		lastFlush: s.clock.Now(),
+	}
+
+	if array {
+		if err := sw.write([]byte("[")); err != nil {
+			return err
+		}
+	}
+
+	var streamErr error
+	for v, err := range seq {
+		if ctx.Err() != nil {
+			break
+		}
+
+		if err != nil {
+			streamErr = err
+			break
+		}
+
+		data, err := json.Marshal(v)
+		if err != nil {
+			streamErr = fmt.Errorf("encode stream record: %w", err)
+			break
+		}
+
+		if err := sw.writeRecord(data); err != nil {
+			return err
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if streamErr != nil {
+		if err := sw.writeError(r, streamErr); err != nil {
+			return errors.Join(streamErr, err)
+		}
+	}
+
+	if array {
+		if err := sw.write([]byte("]\n")); err != nil {
+			return errors.Join(streamErr, err)
+		}
+	}
+
+	if err := sw.flush(); err != nil {
+		return errors.Join(streamErr, err)
+	}
+
+	return streamErr
+}
+
+// streamWriter writes the records of a stream and flushes them.
+type streamWriter struct {
+	w        http.ResponseWriter
+	rc       *http.ResponseController
+	streamer *streamer
+	array    bool
+
+	// records is the number of records written, and pending the number written since the last flush.
+	records   int
+	pending   int
+	lastFlush time.Time
+}
+
+// writeRecord writes an encoded record, flushing if enough records are pending or enough time has passed.
+func (sw *streamWriter) writeRecord(data []byte) error {
+	if sw.array && sw.records > 0 {
+		data = append([]byte(","), data...)
+	}
+	if !sw.array {
+		data = append(data, '\n')
+	}
+
+	if err := sw.write(data); err != nil {
+		return err
+	}
+
+	sw.records++
+	sw.pending++
+	if sw.pending >= sw.streamer.flushEvery || sw.streamer.clock.Now().Sub(sw.lastFlush) >= sw.streamer.flushInterval {
+		return sw.flush()
+	}
+	return nil
+}
+
+// writeError writes the final record for a failed stream and sets the error trailer.
+func (sw *streamWriter) writeError(r *http.Request, err error) error {
+	var (
+		msg     *HTTPError
+		errorer httpErrorer
+	)
+	if !errors.As(err, &msg) {
+		if errors.As(err, &errorer) {
+			msg = errorer.HTTPError()
+		} else {
+			msg = NewHTTPError(http.StatusInternalServerError, err)
+		}
+	}
+	msg.RequestId = RequestIDFromContext(r.Context())
+
+	data, err := json.Marshal(&StreamErrorRecord{Error: msg})
+	if err != nil {
+		return fmt.Errorf("encode stream error: %w", err)
+	}
+
+	sw.w.Header().Set(HeaderStreamError, msg.Detail)
+	return sw.writeRecord(data)
+}
+
+func (sw *streamWriter) write(data []byte) error {
+	if _, err := sw.w.Write(data); err != nil {
+		return fmt.Errorf("write stream: %w", err)
+	}
+	return nil
+}
+
+// flush flushes the response if the writer supports it.
+func (sw *streamWriter) flush() error {
+	sw.pending = 0
+	sw.lastFlush = sw.streamer.clock.Now()
+
+	if err := sw.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return fmt.Errorf("flush stream: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package uhttp
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jacobbrewer1/uhttp/uhttptest"
+	"github.com/stretchr/testify/require"
+)
+
+type streamItem struct {
+	ID int `json:"id"`
+}
+
+// flushRecorder counts the flushes of a response.
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushes int
+}
+
+func (f *flushRecorder) Flush() {
+	f.flushes++
+	f.ResponseRecorder.Flush()
+}
+
+// failingSeq yields the items and then the error.
+func failingSeq(items []streamItem, err error) iter.Seq2[streamItem, error] {
+	return func(yield func(streamItem, error) bool) {
+		for _, item := range items {
+			if !yield(item, nil) {
+				return
+			}
+		}
+		yield(streamItem{}, err)
+	}
+}
+
+func TestStream(t *testing.T) {
+	items := []streamItem{{ID: 1}, {ID: 2}}
+
+	tests := []struct {
+		name            string
+		stream          func(w http.ResponseWriter, r *http.Request) error
+		wantErr         string
+		wantContentType string
+		wantBody        string
+		wantTrailer     string
+	}{
+		{
+			name: "ndjson",
+			stream: func(w http.ResponseWriter, r *http.Request) error {
+				return StreamNDJSON(w, r, http.StatusOK, FromSeq(slices.Values(items)))
+			},
+			wantContentType: ContentTypeNDJSON,
+			wantBody:        "{\"id\":1}\n{\"id\":2}\n",
+		},
+		{
+			name: "ndjson empty",
+			stream: func(w http.ResponseWriter, r *http.Request) error {
+				return StreamNDJSON(w, r, http.StatusOK, FromSeq(slices.Values([]streamItem{})))
+			},
+			wantContentType: ContentTypeNDJSON,
+			wantBody:        "",
+		},
+		{
+			name: "ndjson error",
+			stream: func(w http.ResponseWriter, r *http.Request) error {
+				return StreamNDJSON(w, r, http.StatusOK, failingSeq(items, errors.New("database gone")))
+			},
+			wantErr:         "database gone",
+			wantContentType: ContentTypeNDJSON,
+			wantBody: "{\"id\":1}\n{\"id\":2}\n" +
+				`{"error":{"detail":"database gone","details":null,"request_id":"123","status":500,"title":"Internal Server Error"}}` + "\n",
+			wantTrailer: "database gone",
+		},
+		{
+			name: "array",
+			stream: func(w http.ResponseWriter, r *http.Request) error {
+				return StreamJSONArray(w, r, http.StatusOK, FromSeq(slices.Values(items)))
+			},
+			wantContentType: ContentTypeJSON,
+			wantBody:        "[{\"id\":1},{\"id\":2}]\n",
+		},
+		{
+			name: "array empty",
+			stream: func(w http.ResponseWriter, r *http.Request) error {
+				return StreamJSONArray(w, r, http.StatusOK, FromSeq(slices.Values([]streamItem{})))
+			},
+			wantContentType: ContentTypeJSON,
+			wantBody:        "[]\n",
+		},
+		{
+			name: "array error",
+			stream: func(w http.ResponseWriter, r *http.Request) error {
+				return StreamJSONArray(w, r, http.StatusOK, failingSeq(items[:1], NewHTTPError(http.StatusBadGateway, errors.New("upstream failed"))))
+			},
+			wantErr:         "upstream failed",
+			wantContentType: ContentTypeJSON,
+			wantBody: `[{"id":1},` +
+				`{"error":{"detail":"upstream failed","details":null,"request_id":"123","status":502,"title":"Bad Gateway"}}]` + "\n",
+			wantTrailer: "upstream failed",
+		},
+		{
+			name: "encode error",
+			stream: func(w http.ResponseWriter, r *http.Request) error {
+				return StreamNDJSON(w, r, http.StatusOK, FromSeq(slices.Values([]any{1, make(chan int)})))
+			},
+			wantErr:         "encode stream record: json: unsupported type: chan int",
+			wantContentType: ContentTypeNDJSON,
+			wantBody: "1\n" +
+				`{"error":{"detail":"encode stream record: json: unsupported type: chan int","details":null,"request_id":"123","status":500,"title":"Internal Server Error"}}` + "\n",
+			wantTrailer: "encode stream record: json: unsupported type: chan int",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			r.Header.Set(requestIDHeader, "123")
+			r = r.WithContext(RequestIDToContext(r.Context(), r))
+
+			w := httptest.NewRecorder()
+			err := tt.stream(NewResponseWriter(w), r)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tt.wantErr)
+			}
+
+			resp := w.Result()
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			require.Equal(t, tt.wantContentType, resp.Header.Get(HeaderContentType))
+			require.Equal(t, tt.wantBody, w.Body.String())
+			require.Equal(t, tt.wantTrailer, resp.Trailer.Get(HeaderStreamError))
+		})
+	}
+}
+
+func TestStream_Flush(t *testing.T) {
+	clock := uhttptest.NewFakeClock(time.Now())
+	seq := func(yield func(streamItem, error) bool) {
+		for i := range 5 {
+			if i == 3 {
+				clock.Advance(time.Second)
+			}
+			if !yield(streamItem{ID: i}, nil) {
+				return
+			}
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	w := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+
+	err := StreamNDJSON(NewResponseWriter(w), r, http.StatusOK, seq,
+		WithStreamFlushEvery(2),
+		WithStreamFlushInterval(time.Second),
+		WithStreamClock(clock),
+	)
+	require.NoError(t, err)
+
+	// After the second record, after the fourth as the interval has passed, and at the end.
+	require.Equal(t, 3, w.flushes)
+}
+
+func TestStream_ContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := httptest.NewRequest(http.MethodGet, "/", http.NoBody).WithContext(ctx)
+
+	// The channel never sends, so the stream waits on it until the context is cancelled.
+	ch := make(chan streamItem)
+	seq := func(yield func(streamItem, error) bool) {
+		if !yield(streamItem{ID: 1}, nil) {
+			return
+		}
+		cancel()
+		for v, err := range FromChan(ctx, ch) {
+			if !yield(v, err) {
+				return
+			}
+		}
+	}
+
+	w := httptest.NewRecorder()
+	err := StreamJSONArray(w, r, http.StatusOK, seq)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, `[{"id":1}`, w.Body.String())
+}
+
+func TestFromChan(t *testing.T) {
+	ch := make(chan int, 3)
+	ch <- 1
+	ch <- 2
+	ch <- 3
+	close(ch)
+
+	got := make([]int, 0)
+	for v, err := range FromChan(context.Background(), ch) {
+		require.NoError(t, err)
+		got = append(got, v)
+	}
+	require.Equal(t, []int{1, 2, 3}, got)
+}
@@ -0,0 +1,255 @@
+package uhttp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	// validateRule is the rule reported for errors returned from a Validator that are not a ValidationError.
+	validateRule = "validate"
+)
+
+// Validator is implemented by request types that validate themselves. It is called after any tag validation.
+type Validator interface {
+	// Validate returns an error if the value is invalid. Returning a ValidationError reports each violation
+	// separately.
+	Validate() error
+}
+
+// Violation describes a single field that failed validation.
+type Violation struct {
+	// Pointer is the JSON Pointer (RFC 6901) to the invalid field, or empty if the violation is not about one field.
+	Pointer string `json:"pointer" xml:"pointer"`
+
+	// Rule is the rule that failed, for example "required" or "max".
+	Rule string `json:"rule" xml:"rule"`
+
+	// Message describes the violation.
+	Message string `json:"message" xml:"message"`
+}
+
+// ValidationError is returned when a value fails validation. It is written as a 422 with each violation in the
+// details.
+type ValidationError struct {
+	Violations []Violation
+}
+
+// NewValidationError creates a new ValidationError with the violations.
+func NewValidationError(violations ...Violation) *ValidationError {
+	return &ValidationError{
+		Violations: violations,
+	}
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		if v.Pointer == "" {
+			msgs = append(msgs, v.Message)
+			continue
+		}
+		msgs = append(msgs, v.Pointer+": "+v.Message)
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// StatusCode returns the HTTP status code for the error.
+func (e *ValidationError) StatusCode() int {
+	return http.StatusUnprocessableEntity
+}
+
+// HTTPError converts the error to an HTTPError, with a violation in the details for each invalid field.
+func (e *ValidationError) HTTPError() *HTTPError {
+	details := make([]any, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		details = append(details, v)
+	}
+
+	return NewHTTPError(http.StatusUnprocessableEntity, errors.New("validation failed"), details...)
+}
+
+var (
+	// tagValidator validates the `validate` struct tags. It is created on first use as building it is not cheap.
+	tagValidator = sync.OnceValue(func() *validator.Validate {
+		v := validator.New(validator.WithRequiredStructEnabled())
+		v.RegisterTagNameFunc(jsonFieldName)
+		return v
+	})
+)
+
+// Validate validates v, first against its `validate` struct tags, using the rules of
+// github.com/go-playground/validator, and then by calling Validate if it implements Validator. Every violation is
+// returned in a ValidationError. The Validator is only called when the tags pass.
+func Validate(v any) error {
+	if err := validateTags(v); err != nil {
+		return err
+	}
+
+	validatable, ok := v.(Validator)
+	if !ok {
+		return nil
+	}
+
+	err := validatable.Validate()
+	if err == nil {
+		return nil
+	}
+
+	validationErr := new(ValidationError)
+	if errors.As(err, &validationErr) {
+		return validationErr
+	}
+
+	return NewValidationError(Violation{
+		Rule:    validateRule,
+		Message: err.Error(),
+	})
+}
+
+// DecodeAndValidateRequestJSON decodes the JSON body of the request into v and validates it. Decoding failures are
+// returned as a DecodeError and validation failures as a ValidationError, both of which DecodeErrorHandler can write.
+func DecodeAndValidateRequestJSON[T any](r *http.Request, v *T, opts ...DecodeOption) error {
+	if err := DecodeRequestJSON(r, v, opts...); err != nil {
+		return err
+	}
+	return Validate(v)
+}
+
+// validateTags validates the struct tags of v, returning nil if v is not a struct.
+func validateTags(v any) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	err := tagValidator().Struct(rv.Interface())
+	if err == nil {
+		return nil
+	}
+
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	violations := make([]Violation, 0, len(fieldErrs))
+	for _, fieldErr := range fieldErrs {
+		violations = append(violations, Violation{
+			Pointer: namespaceToPointer(fieldErr.Namespace()),
+			Rule:    fieldErr.Tag(),
+			Message: violationMessage(fieldErr),
+		})
+	}
+
+	return NewValidationError(violations...)
+}
+
+// jsonFieldName names struct fields by their JSON name, so that violations point into the request body.
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	default:
+		return name
+	}
+}
+
+// namespaceToPointer converts a validator namespace, such as "Request.items[0].name", into a JSON Pointer such as
+// "/items/0/name". The first element of the namespace is the name of the top level struct and is dropped.
+func namespaceToPointer(namespace string) string {
+	_, path, ok := strings.Cut(namespace, ".")
+	if !ok {
+		return ""
+	}
+
+	var sb strings.Builder
+	for part := range strings.SplitSeq(path, ".") {
+		name, index, _ := strings.Cut(part, "[")
+		sb.WriteString("/" + escapePointerToken(name))
+
+		for index != "" {
+			var token string
+			token, index, _ = strings.Cut(index, "]")
+			sb.WriteString("/" + escapePointerToken(token))
+			index = strings.TrimPrefix(index, "[")
+		}
+	}
+
+	return sb.String()
+}
+
+// escapePointerToken escapes a reference token of a JSON Pointer.
+func escapePointerToken(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+// violationMessage describes a failed validation rule.
+func violationMessage(fieldErr validator.FieldError) string {
+	param := fieldErr.Param()
+
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "min", "gte":
+		return "must be at least " + param + lengthUnit(fieldErr)
+	case "max", "lte":
+		return "must be at most " + param + lengthUnit(fieldErr)
+	case "gt":
+		return "must be greater than " + param + lengthUnit(fieldErr)
+	case "lt":
+		return "must be less than " + param + lengthUnit(fieldErr)
+	case "len":
+		return "must be exactly " + param + lengthUnit(fieldErr)
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(param), ", ")
+	case "email":
+		return "must be a valid email address"
+	case "url", "uri":
+		return "must be a valid URL"
+	case "uuid", "uuid4":
+		return "must be a valid UUID"
+	}
+
+	if param != "" {
+		return "must satisfy " + fieldErr.Tag() + "=" + param
+	}
+	return "must satisfy " + fieldErr.Tag()
+}
+
+// lengthUnit returns the unit of the parameter of a size rule, which counts characters for strings and items for
+// slices and maps.
+func lengthUnit(fieldErr validator.FieldError) string {
+	plural := func(unit string) string {
+		if n, err := strconv.Atoi(fieldErr.Param()); err == nil && n == 1 {
+			return " " + unit
+		}
+		return " " + unit + "s"
+	}
+
+	switch fieldErr.Kind() { // nolint:exhaustive // Only sized kinds have a unit
+	case reflect.String:
+		return plural("character")
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return plural("item")
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,130 @@
+package uhttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type validationItem struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type validationRequest struct {
+	Name  string           `json:"name" validate:"required,max=5"`
+	Role  string           `json:"role" validate:"omitempty,oneof=admin user"`
+	Items []validationItem `json:"items" validate:"min=1,dive"`
+	Tags  map[string]int   `json:"tags,omitempty"`
+}
+
+func (r *validationRequest) Validate() error {
+	if r.Name == "admin" && r.Role != "admin" {
+		return NewValidationError(Violation{Pointer: "/role", Rule: "admin", Message: "must be admin"})
+	}
+	if r.Name == "error" {
+		return errors.New("name is reserved")
+	}
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want []Violation
+	}{
+		{
+			name: "valid",
+			v:    &validationRequest{Name: "a", Items: []validationItem{{Name: "b"}}},
+		},
+		{
+			name: "tags",
+			v:    &validationRequest{Name: "toolong", Role: "owner", Items: []validationItem{{Name: "b"}, {}}},
+			want: []Violation{
+				{Pointer: "/name", Rule: "max", Message: "must be at most 5 characters"},
+				{Pointer: "/role", Rule: "oneof", Message: "must be one of: admin, user"},
+				{Pointer: "/items/1/name", Rule: "required", Message: "is required"},
+			},
+		},
+		{
+			name: "empty slice",
+			v:    &validationRequest{Name: "a"},
+			want: []Violation{
+				{Pointer: "/items", Rule: "min", Message: "must be at least 1 item"},
+			},
+		},
+		{
+			name: "validator violations",
+			v:    &validationRequest{Name: "admin", Items: []validationItem{{Name: "b"}}},
+			want: []Violation{
+				{Pointer: "/role", Rule: "admin", Message: "must be admin"},
+			},
+		},
+		{
+			name: "validator error",
+			v:    &validationRequest{Name: "error", Items: []validationItem{{Name: "b"}}},
+			want: []Violation{
+				{Rule: "validate", Message: "name is reserved"},
+			},
+		},
+		{
+			name: "not a struct",
+			v:    []string{"a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.v)
+			if tt.want == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			validationErr := new(ValidationError)
+			require.ErrorAs(t, err, &validationErr)
+			require.Equal(t, tt.want, validationErr.Violations)
+		})
+	}
+}
+
+func TestNamespaceToPointer(t *testing.T) {
+	tests := map[string]string{
+		"Request":                   "",
+		"Request.name":              "/name",
+		"Request.items[0].name":     "/items/0/name",
+		"Request.matrix[1][2]":      "/matrix/1/2",
+		"Request.tags[a/b~c].value": "/tags/a~1b~0c/value",
+	}
+
+	for namespace, want := range tests {
+		t.Run(namespace, func(t *testing.T) {
+			require.Equal(t, want, namespaceToPointer(namespace))
+		})
+	}
+}
+
+func TestDecodeAndValidateRequestJSON(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"toolong","items":[{}]}`))
+
+	got := new(validationRequest)
+	err := DecodeAndValidateRequestJSON(r, got)
+	require.Error(t, err)
+
+	w := httptest.NewRecorder()
+	DecodeErrorHandler(w, r, err)
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	resp := new(HTTPError)
+	require.NoError(t, DecodeJSON(w.Result().Body, resp))
+	require.Equal(t, http.StatusUnprocessableEntity, resp.Status)
+	require.Equal(t, "validation failed", resp.Detail)
+	require.Equal(t, []any{
+		map[string]any{"pointer": "/name", "rule": "max", "message": "must be at most 5 characters"},
+		map[string]any{"pointer": "/items/0/name", "rule": "required", "message": "is required"},
+	}, resp.Details)
+}
@@ -19,6 +19,13 @@ func WithDefaultStatusCode(statusCode int) WriterOpt {
 	}
 }
 
+// WithWriterClock sets the clock used to time the request. The default is the system clock.
+func WithWriterClock(clock Clock) WriterOpt {
+	return func(w *ResponseWriter) {
+		w.clock = clock
+	}
+}
+
 // WithDefaultHeader sets the default headers for the response writer.
 func WithDefaultHeader(header, value string) WriterOpt {
 	return func(w *ResponseWriter) {